@@ -9,9 +9,16 @@ import (
 	"log/slog"
 	"ml_facade/config"
 	"ml_facade/internal/api"
+	"ml_facade/internal/auth"
 	"ml_facade/internal/consumer"
+	"ml_facade/internal/grpcapi"
+	"ml_facade/internal/health"
+	"ml_facade/internal/jobs"
+	"ml_facade/internal/logging"
 	"ml_facade/internal/models/postgres_models"
 	"ml_facade/internal/models/redis_models"
+	"ml_facade/internal/ratelimit"
+	"ml_facade/internal/schema"
 	"ml_facade/internal/service"
 	"os"
 	"os/signal"
@@ -26,6 +33,7 @@ type application struct {
 	config         config.Config
 	logger         *slog.Logger
 	server         *api.Server
+	grpcServer     *grpcapi.Server
 	rabbitConsumer *consumer.RabbitMQConsumer
 }
 
@@ -35,7 +43,8 @@ func StartApp(cfg config.Config) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	// The log lines of a request or of a consumer batch carry its ID
+	logger := slog.New(logging.NewHandler(slog.NewJSONHandler(os.Stdout, nil)))
 
 	pdb, err := postgresDB(cfg.PostgresDB)
 	if err != nil {
@@ -51,34 +60,86 @@ func StartApp(cfg config.Config) {
 	}
 	logger.Info("redis database connection pool established")
 
-	thresholdModel := redis_models.ThresholdModel{RedisDB: rdb}
-	sensorModel := postgres_models.SensorModel{PostgresDB: pdb}
-
-	mlService, err := service.NewMlService(cfg.MlService, logger, &sensorModel, &thresholdModel, &wg)
+	sensorSchema, err := schema.Load(cfg.SensorSchema)
 	if err != nil {
-		logger.Error(fmt.Sprintf("error creating ml service client: %v", err))
+		logger.Error(fmt.Sprintf("failed to load sensor schema: %s", err))
 		os.Exit(1)
 	}
+	logger.Info(fmt.Sprintf("sensor schema loaded with %d sensors", sensorSchema.Len()))
+
+	thresholdModel := redis_models.ThresholdModel{RedisDB: rdb}
+	sensorModel := postgres_models.SensorModel{PostgresDB: pdb}
+	predictionModel := redis_models.PredictionModel{RedisDB: rdb}
+	dedupModel := redis_models.DedupModel{RedisDB: rdb}
+	idempotencyModel := redis_models.IdempotencyModel{RedisDB: rdb}
+	jobModel := postgres_models.JobModel{PostgresDB: pdb}
+	apiKeyModel := postgres_models.ApiKeyModel{PostgresDB: pdb}
+	rateLimitModel := redis_models.RateLimitModel{RedisDB: rdb}
+
+	clientLimiter := ratelimit.New(cfg.ApiServer.Limiter, "client", &rateLimitModel, logger)
+	ipLimiter := ratelimit.New(cfg.ApiServer.IPLimiter, "ip", &rateLimitModel, logger)
+	machineLimiter := ratelimit.New(cfg.MlService.MachineLimiter, "machine", &rateLimitModel, logger)
+
+	// The application starts even if the ml service is not reachable yet,
+	// the monitor keeps reconnecting to it in the background
+	mlService := service.NewMlService(cfg.MlService, cfg.Validation, logger, sensorSchema, &sensorModel, &thresholdModel, &predictionModel, &dedupModel, machineLimiter, &wg)
 	logger.Info("ml service client successfully initialized")
 
-	server := api.NewApiServer(cfg, logger, mlService, &thresholdModel, version, &wg)
 	rabbitmqConsumer := consumer.NewRabbitMQConsumer(cfg.RabbitMQConsumer, logger, mlService, &wg)
+	jobRunner := jobs.NewRunner(cfg.Jobs, logger, mlService, &jobModel, &wg)
+
+	checks := []health.Check{
+		{Name: "postgres", Critical: true, Probe: sensorModel.Ping},
+		{Name: "redis", Critical: true, Probe: thresholdModel.Ping},
+		{Name: "ml_service", Critical: false, Probe: mlService.Ping},
+		{Name: "rabbitmq", Critical: false, Probe: rabbitmqConsumer.Ping},
+	}
+
+	authenticator := auth.NewAuthenticator(cfg.Auth, logger, &apiKeyModel)
+	if !cfg.Auth.Enabled {
+		logger.Warn("api key authentication is disabled")
+	}
+
+	server := api.NewApiServer(cfg, logger, mlService, &thresholdModel, &sensorModel, &predictionModel, &idempotencyModel, &jobModel, &apiKeyModel, authenticator, clientLimiter, ipLimiter, checks, version, &wg)
+
+	grpcServer := grpcapi.NewServer(cfg, logger, mlService, authenticator, clientLimiter, ipLimiter, &wg)
 
 	app := &application{
 		config:         cfg,
 		logger:         logger,
 		server:         server,
+		grpcServer:     grpcServer,
 		rabbitConsumer: rabbitmqConsumer,
 	}
 
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		mlService.Monitor(ctx)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		mlService.WatchThresholds(ctx)
+	}()
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		if err := app.server.Serve(ctx); err != nil {
-			app.logger.Error("api server error: %v", err)
+			app.logger.Error(fmt.Sprintf("api server error: %v", err))
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := app.grpcServer.Serve(ctx); err != nil {
+			app.logger.Error(fmt.Sprintf("grpc server error: %v", err))
 		}
 	}()
 
@@ -86,10 +147,16 @@ func StartApp(cfg config.Config) {
 	go func() {
 		defer wg.Done()
 		if err := app.rabbitConsumer.Serve(ctx); err != nil {
-			app.logger.Error("rabbit consumer error: %v", err)
+			app.logger.Error(fmt.Sprintf("rabbit consumer error: %v", err))
 		}
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		jobRunner.Run(ctx)
+	}()
+
 	sig := <-signalChan
 	logger.Info(fmt.Sprintf("received signal %v. Initiating shutdown...", sig))
 
@@ -12,10 +12,27 @@ func main() {
 	var cfg config.Config
 
 	flag.StringVar(&cfg.Env, "env", os.Getenv("ENVIRONMENT"), "Environment (development|staging|production)")
+	flag.StringVar(&cfg.SensorSchema, "sensor-schema", os.Getenv("SENSOR_SCHEMA"), "Path to the JSON sensor schema (defaults to the 52 sensors of the pump dataset)")
 	flag.IntVar(&cfg.ApiServer.Port, "port", 4000, "API server port")
-	flag.IntVar(&cfg.ApiServer.Limiter.Rps, "rate-limiter", 500, "Rate limiter")
-	flag.IntVar(&cfg.ApiServer.Limiter.Burst, "rate-limiter-burst", 20, "Rate limiter burst")
-	flag.BoolVar(&cfg.ApiServer.Limiter.Enabled, "rate-limiter-enabled", true, "Enable rate limiter")
+	flag.IntVar(&cfg.ApiServer.Limiter.Rps, "rate-limiter", 500, "Requests per second of every client, identified by its API key or its IP address")
+	flag.IntVar(&cfg.ApiServer.Limiter.Burst, "rate-limiter-burst", 20, "Rate limiter burst of every client")
+	flag.BoolVar(&cfg.ApiServer.Limiter.Enabled, "rate-limiter-enabled", true, "Enable the rate limiter of the clients")
+	flag.DurationVar(&cfg.ApiServer.Limiter.IdleTimeout, "rate-limiter-idle-timeout", 5*time.Minute, "How long the rate limiter of an idle client is kept")
+	flag.BoolVar(&cfg.ApiServer.Limiter.Redis, "rate-limiter-redis", false, "Share the rate limits of the clients between the replicas through Redis")
+	flag.IntVar(&cfg.ApiServer.IPLimiter.Rps, "ip-rate-limiter", 5000, "Requests per second of every IP address, checked before the API key of the requests")
+	flag.IntVar(&cfg.ApiServer.IPLimiter.Burst, "ip-rate-limiter-burst", 200, "Rate limiter burst of every IP address")
+	flag.BoolVar(&cfg.ApiServer.IPLimiter.Enabled, "ip-rate-limiter-enabled", true, "Enable the rate limiter of the IP addresses")
+	flag.DurationVar(&cfg.ApiServer.IPLimiter.IdleTimeout, "ip-rate-limiter-idle-timeout", 5*time.Minute, "How long the rate limiter of an idle IP address is kept")
+	flag.BoolVar(&cfg.ApiServer.IPLimiter.Redis, "ip-rate-limiter-redis", false, "Share the rate limits of the IP addresses between the replicas through Redis")
+	flag.DurationVar(&cfg.ApiServer.StreamHeartbeat, "stream-heartbeat", 15*time.Second, "Interval between heartbeats of the prediction streams")
+	flag.IntVar(&cfg.ApiServer.NDJSONBatchSize, "ndjson-batch-size", 50, "Maximum number of readings of an NDJSON predict request scored at once")
+	flag.DurationVar(&cfg.ApiServer.NDJSONBatchWait, "ndjson-batch-wait", 100*time.Millisecond, "Maximum time the readings of an NDJSON predict request wait for their micro-batch to fill up")
+	flag.DurationVar(&cfg.ApiServer.IdempotencyWindow, "idempotency-window", 24*time.Hour, "How long the responses of requests sent with an Idempotency-Key header are replayed (0 disables idempotency keys)")
+	flag.IntVar(&cfg.GrpcServer.Port, "grpc-port", 50051, "gRPC server port")
+	flag.IntVar(&cfg.GrpcServer.StreamBatchSize, "grpc-stream-batch-size", 50, "Maximum number of readings of a gRPC stream scored at once")
+	flag.DurationVar(&cfg.GrpcServer.StreamBatchWait, "grpc-stream-batch-wait", 100*time.Millisecond, "Maximum time the readings of a gRPC stream wait for their micro-batch to fill up")
+	flag.BoolVar(&cfg.Auth.Enabled, "auth-enabled", true, "Require an API key on the HTTP and gRPC APIs")
+	flag.StringVar(&cfg.Auth.AdminKey, "admin-api-key", os.Getenv("ADMIN_API_KEY"), "API key granted the admin scope, used to create the stored API keys")
 	flag.StringVar(&cfg.PostgresDB.Host, "db-host", os.Getenv("MONITORING_DB_HOST"), "PostgreSQL Host")
 	flag.StringVar(&cfg.PostgresDB.Port, "db-port", os.Getenv("MONITORING_DB_PORT"), "PostgreSQL Port")
 	flag.StringVar(&cfg.PostgresDB.Username, "db-username", os.Getenv("MONITORING_DB_USERNAME"), "PostgreSQL Username")
@@ -28,11 +45,40 @@ func main() {
 	flag.StringVar(&cfg.RedisDB.Port, "rdb-port", os.Getenv("REDIS_DB_PORT"), "Redis Port")
 	flag.StringVar(&cfg.MlService.Host, "ml-service-host", os.Getenv("ML_SERVICE_HOST"), "ML Service Host")
 	flag.StringVar(&cfg.MlService.Port, "ml-service-port", os.Getenv("ML_SERVICE_PORT"), "ML Service Port")
+	flag.DurationVar(&cfg.MlService.HealthInterval, "ml-service-health-interval", 5*time.Second, "ML Service health check interval")
+	flag.DurationVar(&cfg.MlService.Timeout, "ml-service-timeout", 2*time.Second, "ML Service request timeout")
+	flag.IntVar(&cfg.MlService.Breaker.FailureThreshold, "ml-service-breaker-failures", 5, "ML Service consecutive failures before the circuit breaker opens")
+	flag.DurationVar(&cfg.MlService.Breaker.OpenTimeout, "ml-service-breaker-open-timeout", 10*time.Second, "ML Service circuit breaker open duration before probing")
+	flag.IntVar(&cfg.MlService.Breaker.HalfOpenRequests, "ml-service-breaker-probes", 1, "ML Service successful probes needed to close the circuit breaker")
+	flag.Int64Var(&cfg.Validation.MaxBodyBytes, "max-body-bytes", 4<<20, "Maximum size of a request body or message, in bytes")
+	flag.IntVar(&cfg.Validation.MinBatchSize, "min-batch-size", 1, "Minimum number of readings per request")
+	flag.IntVar(&cfg.Validation.MaxBatchSize, "max-batch-size", 1000, "Maximum number of readings per request")
+	flag.IntVar(&cfg.Jobs.Workers, "job-workers", 2, "Number of workers processing the predict jobs")
+	flag.IntVar(&cfg.Jobs.ChunkSize, "job-chunk-size", 500, "Number of readings of a predict job scored at once, at most max-batch-size")
+	flag.IntVar(&cfg.Jobs.MaxReadings, "job-max-readings", 100000, "Maximum number of readings per predict job")
+	flag.Int64Var(&cfg.Jobs.MaxBodyBytes, "job-max-body-bytes", 64<<20, "Maximum size of a predict job request body, in bytes")
+	flag.DurationVar(&cfg.Jobs.PollInterval, "job-poll-interval", time.Second, "Interval between checks for new predict jobs")
+	flag.DurationVar(&cfg.Jobs.Lease, "job-lease", time.Minute, "How long a predict job stays assigned to a worker that stopped making progress")
+	flag.IntVar(&cfg.Import.ChunkSize, "import-chunk-size", 500, "Number of rows of a CSV import scored at once")
+	flag.Int64Var(&cfg.Import.MaxBodyBytes, "import-max-body-bytes", 1<<30, "Maximum size of a CSV import, in bytes")
+	flag.DurationVar(&cfg.Import.Timeout, "import-timeout", 10*time.Minute, "Maximum duration of a CSV import")
+	flag.DurationVar(&cfg.MlService.LatenessTolerance, "lateness-tolerance", 5*time.Minute, "How far behind the latest reading of its machine a reading may be before it is flagged as late")
+	flag.DurationVar(&cfg.MlService.DedupWindow, "dedup-window", 24*time.Hour, "How long ingested readings are remembered to detect duplicates (0 disables deduplication)")
+	flag.IntVar(&cfg.MlService.MachineLimiter.Rps, "machine-rate-limiter", 100, "Readings per second of every machine sent through the HTTP and gRPC APIs")
+	flag.IntVar(&cfg.MlService.MachineLimiter.Burst, "machine-rate-limiter-burst", 1000, "Rate limiter burst of every machine, in readings")
+	flag.BoolVar(&cfg.MlService.MachineLimiter.Enabled, "machine-rate-limiter-enabled", false, "Enable the rate limiter of the machines")
+	flag.DurationVar(&cfg.MlService.MachineLimiter.IdleTimeout, "machine-rate-limiter-idle-timeout", 5*time.Minute, "How long the rate limiter of an idle machine is kept")
+	flag.BoolVar(&cfg.MlService.MachineLimiter.Redis, "machine-rate-limiter-redis", false, "Share the rate limits of the machines between the replicas through Redis")
 	flag.StringVar(&cfg.RabbitMQConsumer.URI, "rabbitmq-uri", os.Getenv("RABBITMQ_URI"), "RabbitMQ URI")
 	flag.StringVar(&cfg.RabbitMQConsumer.Queue, "rabbitmq-queue", os.Getenv("RABBITMQ_QUEUE"), "RabbitMQ Queue")
-	flag.IntVar(&cfg.RabbitMQConsumer.NumWorkers, "rabbitmq-workers", 100, "RabbitMQ number of workers")
+	flag.IntVar(&cfg.RabbitMQConsumer.NumWorkers, "rabbitmq-workers", 100, "RabbitMQ number of workers, each one processing the messages of a subset of machines in order")
 	flag.IntVar(&cfg.RabbitMQConsumer.BatchSize, "rabbitmq-batchsize", 20, "RabbitMQ batch size")
 	flag.DurationVar(&cfg.RabbitMQConsumer.BatchTimeout, "rabbitmq-batchtimeout", 50*time.Millisecond, "RabbitMQ batch timeout")
+	flag.IntVar(&cfg.RabbitMQConsumer.Prefetch, "rabbitmq-prefetch", 0, "RabbitMQ prefetch count (0 means workers * batch size)")
+	flag.IntVar(&cfg.RabbitMQConsumer.MaxRetries, "rabbitmq-max-retries", 5, "RabbitMQ max delivery retries before dead-lettering")
+	flag.DurationVar(&cfg.RabbitMQConsumer.RetryDelay, "rabbitmq-retry-delay", 5*time.Second, "RabbitMQ delay before a failed message is redelivered")
+	flag.StringVar(&cfg.RabbitMQConsumer.DeadLetterExchange, "rabbitmq-dead-letter-exchange", os.Getenv("RABBITMQ_DEAD_LETTER_EXCHANGE"), "RabbitMQ dead letter exchange (defaults to <queue>.dlx)")
+	flag.StringVar(&cfg.RabbitMQConsumer.DeadLetterQueue, "rabbitmq-dead-letter-queue", os.Getenv("RABBITMQ_DEAD_LETTER_QUEUE"), "RabbitMQ dead letter queue (defaults to <queue>.dead)")
 
 	flag.Parse()
 
@@ -6,17 +6,28 @@ import (
 )
 
 type CfgRabbitMQConsumer struct {
-	URI          string
-	Queue        string
-	NumWorkers   int
-	BatchSize    int
-	BatchTimeout time.Duration
+	URI                string
+	Queue              string
+	NumWorkers         int
+	BatchSize          int
+	BatchTimeout       time.Duration
+	Prefetch           int
+	MaxRetries         int
+	RetryDelay         time.Duration
+	DeadLetterExchange string
+	DeadLetterQueue    string
 }
 
+// CfgLimiter holds the settings of a rate limiter giving every client, or every
+// machine, a bucket of Burst tokens refilled at Rps tokens per second. The buckets
+// unused for IdleTimeout are evicted. With Redis, the buckets are stored in Redis and
+// shared by the replicas of the facade.
 type CfgLimiter struct {
-	Rps     int
-	Burst   int
-	Enabled bool
+	Rps         int
+	Burst       int
+	Enabled     bool
+	IdleTimeout time.Duration
+	Redis       bool
 }
 
 type CfgPostgresDB struct {
@@ -35,22 +46,103 @@ type CfgRedisDB struct {
 	Port string
 }
 
+type CfgCircuitBreaker struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+	HalfOpenRequests int
+}
+
 type CfgMlService struct {
-	Host string
-	Port string
+	Host           string
+	Port           string
+	HealthInterval time.Duration
+	Timeout        time.Duration
+	Breaker        CfgCircuitBreaker
+	// LatenessTolerance is how far behind the latest reading of its machine a reading
+	// may be before it is flagged as late.
+	LatenessTolerance time.Duration
+	// DedupWindow is how long ingested readings are remembered to detect duplicates.
+	// Deduplication is disabled when it is zero.
+	DedupWindow time.Duration
+	// MachineLimiter limits the number of readings per second of every machine sent
+	// through the HTTP and gRPC APIs.
+	MachineLimiter CfgLimiter
+}
+
+// CfgValidation holds the limits the incoming readings are checked against.
+// MaxBodyBytes applies to HTTP request bodies and to AMQP messages alike.
+type CfgValidation struct {
+	MaxBodyBytes int64
+	MinBatchSize int
+	MaxBatchSize int
+}
+
+// CfgJobs holds the settings of the predict jobs. Jobs are scored in chunks of at most
+// ChunkSize readings, each chunk going through the ml service like a predict request.
+type CfgJobs struct {
+	Workers      int
+	ChunkSize    int
+	MaxReadings  int
+	MaxBodyBytes int64
+	PollInterval time.Duration
+	// Lease is how long a job stays assigned to a worker that stopped making progress
+	Lease time.Duration
+}
+
+// CfgImport holds the settings of the CSV imports. The rows of a file are scored in
+// chunks of at most ChunkSize readings while the file is uploaded, the upload being
+// bound by Timeout instead of the timeouts of the server.
+type CfgImport struct {
+	ChunkSize    int
+	MaxBodyBytes int64
+	Timeout      time.Duration
 }
 
 type CfgApiServer struct {
 	Port    int
 	Limiter CfgLimiter
+	// IPLimiter limits the requests of every IP address before they are authenticated,
+	// whatever their API key. Its rate should be well above the one of Limiter, since
+	// the clients behind a NAT share it.
+	IPLimiter       CfgLimiter
+	StreamHeartbeat time.Duration
+	// NDJSON predict requests are scored in micro-batches of at most NDJSONBatchSize
+	// readings, a batch being sent at the latest NDJSONBatchWait after its first reading.
+	NDJSONBatchSize int
+	NDJSONBatchWait time.Duration
+	// IdempotencyWindow is how long the responses of the requests sent with an
+	// Idempotency-Key header are replayed. The header is ignored when it is zero.
+	IdempotencyWindow time.Duration
+}
+
+// CfgGrpcServer holds the settings of the gRPC server. The readings of a StreamReadings
+// call are scored in micro-batches of at most StreamBatchSize readings, a batch being
+// sent at the latest StreamBatchWait after its first reading.
+type CfgGrpcServer struct {
+	Port            int
+	StreamBatchSize int
+	StreamBatchWait time.Duration
+}
+
+// CfgAuth holds the settings of the API key authentication. AdminKey is a key granted
+// the admin scope without being stored, meant to create the first stored keys.
+type CfgAuth struct {
+	Enabled  bool
+	AdminKey string
 }
 
 type Config struct {
 	Env              string
+	SensorSchema     string
 	ApiServer        CfgApiServer
+	GrpcServer       CfgGrpcServer
+	Auth             CfgAuth
 	PostgresDB       CfgPostgresDB
 	RedisDB          CfgRedisDB
 	MlService        CfgMlService
+	Validation       CfgValidation
+	Jobs             CfgJobs
+	Import           CfgImport
 	RabbitMQConsumer CfgRabbitMQConsumer
 }
 
@@ -66,3 +158,30 @@ func (c CfgRedisDB) RedisDBDsn() string {
 func (c CfgMlService) MlServiceUri() string {
 	return fmt.Sprintf("http://%s:%s", c.Host, c.Port)
 }
+
+func (c CfgRabbitMQConsumer) RetryQueueName() string {
+	return fmt.Sprintf("%s.retry", c.Queue)
+}
+
+func (c CfgRabbitMQConsumer) DeadLetterExchangeName() string {
+	if c.DeadLetterExchange != "" {
+		return c.DeadLetterExchange
+	}
+	return fmt.Sprintf("%s.dlx", c.Queue)
+}
+
+func (c CfgRabbitMQConsumer) DeadLetterQueueName() string {
+	if c.DeadLetterQueue != "" {
+		return c.DeadLetterQueue
+	}
+	return fmt.Sprintf("%s.dead", c.Queue)
+}
+
+// PrefetchCount returns the number of unacknowledged messages the broker may push
+// to the consumer. It defaults to enough messages to keep every worker busy.
+func (c CfgRabbitMQConsumer) PrefetchCount() int {
+	if c.Prefetch > 0 {
+		return c.Prefetch
+	}
+	return c.NumWorkers * c.BatchSize
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"context"
+	"ml_facade/internal/models/postgres_models"
+	"net/http"
+)
+
+type contextKey string
+
+const (
+	apiKeyContextKey = contextKey("apiKey")
+	routeContextKey  = contextKey("route")
+)
+
+// contextSetApiKey returns a copy of the request holding the key it was authenticated with.
+func (a *Server) contextSetApiKey(r *http.Request, key *postgres_models.ApiKey) *http.Request {
+	ctx := context.WithValue(r.Context(), apiKeyContextKey, key)
+	return r.WithContext(ctx)
+}
+
+// contextGetApiKey returns the key the request was authenticated with, or nil if the
+// client sent no key.
+func (a *Server) contextGetApiKey(r *http.Request) *postgres_models.ApiKey {
+	key, _ := r.Context().Value(apiKeyContextKey).(*postgres_models.ApiKey)
+	return key
+}
+
+// contextSetRoute returns a copy of the request holding where to record its route.
+func (a *Server) contextSetRoute(r *http.Request, route *string) *http.Request {
+	ctx := context.WithValue(r.Context(), routeContextKey, route)
+	return r.WithContext(ctx)
+}
+
+// contextGetRoute returns where to record the route of the request, or nil if it is
+// not instrumented.
+func (a *Server) contextGetRoute(r *http.Request) *string {
+	route, _ := r.Context().Value(routeContextKey).(*string)
+	return route
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"ml_facade/internal/codec"
+	"net/http"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// writeNegotiated writes a response in the format asked for by the Accept header of the
+// request: data is sent as JSON or MessagePack, and message as Protobuf. JSON is sent
+// when the client accepts none of them, or asks for Protobuf while message is nil.
+func (a *Server) writeNegotiated(w http.ResponseWriter, r *http.Request, status int, data envelope, message proto.Message) error {
+	w.Header().Add("Vary", "Accept")
+
+	var body []byte
+	var err error
+
+	mediaType := codec.Negotiate(r.Header.Get("Accept"))
+	switch {
+	case mediaType == codec.Protobuf && message != nil:
+		body, err = proto.Marshal(message)
+	case mediaType == codec.MsgPack:
+		body, err = codec.MarshalMsgPack(data)
+	default:
+		return a.writeJSON(w, status, data)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+	return err
+}
@@ -2,7 +2,11 @@ package api
 
 import (
 	"fmt"
+	"ml_facade/internal/codec"
+	"ml_facade/internal/service"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 func (a *Server) logError(r *http.Request, err error) {
@@ -11,12 +15,12 @@ func (a *Server) logError(r *http.Request, err error) {
 		uri    = r.URL.RequestURI()
 	)
 
-	a.logger.Error(err.Error(), "method", method, "uri", uri)
+	a.logger.ErrorContext(r.Context(), err.Error(), "method", method, "uri", uri)
 }
 
 func (a *Server) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
 	env := envelope{"error": message}
-	err := a.writeJSON(w, status, env)
+	err := a.writeNegotiated(w, r, status, env, codec.ErrorMessage(message))
 	if err != nil {
 		a.logError(r, err)
 		w.WriteHeader(500)
@@ -30,6 +34,24 @@ func (a *Server) serverErrorResponse(w http.ResponseWriter, r *http.Request, err
 	a.errorResponse(w, r, http.StatusInternalServerError, message)
 }
 
+func (a *Server) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
+	a.errorResponse(w, r, http.StatusBadRequest, err.Error())
+}
+
+func (a *Server) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
+	a.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
+}
+
+func (a *Server) bodyTooLargeResponse(w http.ResponseWriter, r *http.Request, limit int64) {
+	message := fmt.Sprintf("body must not be larger than %d bytes", limit)
+	a.errorResponse(w, r, http.StatusRequestEntityTooLarge, message)
+}
+
+func (a *Server) unsupportedMediaTypeResponse(w http.ResponseWriter, r *http.Request, supported ...string) {
+	message := fmt.Sprintf("the content type must be one of %s", strings.Join(supported, ", "))
+	a.errorResponse(w, r, http.StatusUnsupportedMediaType, message)
+}
+
 func (a *Server) notFoundResponse(w http.ResponseWriter, r *http.Request) {
 	message := "the requested resource could not be found"
 	a.errorResponse(w, r, http.StatusNotFound, message)
@@ -40,7 +62,46 @@ func (a *Server) methodNotAllowedResponse(w http.ResponseWriter, r *http.Request
 	a.errorResponse(w, r, http.StatusMethodNotAllowed, message)
 }
 
-func (a *Server) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
+func (a *Server) serviceUnavailableResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", "5")
+	message := "the ml service is temporarily unavailable, please retry later"
+	a.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
+
+func (a *Server) idempotencyConflictResponse(w http.ResponseWriter, r *http.Request) {
+	message := "a request with the same idempotency key is being processed, please retry later"
+	a.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (a *Server) idempotencyMismatchResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the idempotency key was already used with a different request"
+	a.errorResponse(w, r, http.StatusUnprocessableEntity, message)
+}
+
+func (a *Server) invalidApiKeyResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	message := "invalid or expired api key"
+	a.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (a *Server) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	message := "you must send an api key to access this resource"
+	a.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (a *Server) notPermittedResponse(w http.ResponseWriter, r *http.Request, scope string) {
+	message := fmt.Sprintf("your api key must have the %s scope to access this resource", scope)
+	a.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (a *Server) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request, retryAfter int) {
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
 	message := "rate limit exceeded"
 	a.errorResponse(w, r, http.StatusTooManyRequests, message)
 }
+
+func (a *Server) machineRateLimitExceededResponse(w http.ResponseWriter, r *http.Request, err *service.RateLimitError) {
+	w.Header().Set("Retry-After", strconv.Itoa(err.RetryAfterSeconds()))
+	a.errorResponse(w, r, http.StatusTooManyRequests, err.Error())
+}
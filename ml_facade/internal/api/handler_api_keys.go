@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ml_facade/internal/models/postgres_models"
+	"ml_facade/internal/validator"
+	"net/http"
+	"time"
+)
+
+// maxApiKeyNameLength is the maximum length, in bytes, of the name of a key.
+const maxApiKeyNameLength = 100
+
+// createApiKeyHandler creates an API key with the given scopes and an optional expiry.
+// The plaintext key is only part of this response.
+func (a *Server) createApiKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		a.errorResponse(w, r, http.StatusBadRequest, "invalid JSON in request body")
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Name != "", "name", "must be provided")
+	v.Check(len(input.Name) <= maxApiKeyNameLength, "name", fmt.Sprintf("must not be more than %d bytes long", maxApiKeyNameLength))
+	v.Check(len(input.Scopes) > 0, "scopes", "must contain at least one scope")
+	for _, scope := range input.Scopes {
+		v.Check(validator.PermittedValue(scope, postgres_models.AllScopes...), "scopes", fmt.Sprintf("unknown scope %q", scope))
+	}
+	if input.ExpiresAt != nil {
+		v.Check(input.ExpiresAt.After(time.Now()), "expires_at", "must be in the future")
+	}
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	key, plaintext, err := a.apiKeyModel.Insert(r.Context(), input.Name, input.Scopes, input.ExpiresAt)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = a.writeJSON(w, http.StatusCreated, envelope{"api_key": key, "key": plaintext})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// listApiKeysHandler returns every API key, without their plaintext.
+func (a *Server) listApiKeysHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := a.apiKeyModel.List(r.Context())
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = a.writeJSON(w, http.StatusOK, envelope{"api_keys": keys})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// revokeApiKeyHandler revokes an API key. The key keeps working for a short while on the
+// other replicas, see auth.Authenticator.
+func (a *Server) revokeApiKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = a.apiKeyModel.Revoke(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres_models.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	a.authenticator.Revoked(id)
+
+	err = a.writeJSON(w, http.StatusOK, envelope{"message": "api key successfully revoked"})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
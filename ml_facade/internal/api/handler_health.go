@@ -1,9 +1,16 @@
 package api
 
 import (
+	"ml_facade/internal/health"
 	"net/http"
+	"time"
 )
 
+// checkTimeout bounds the time spent probing a single dependency.
+const checkTimeout = 2 * time.Second
+
+// healthcheckHandler reports that the application is alive. With verbose=true,
+// the status of every dependency is included in the response.
 func (a *Server) healthcheckHandler(w http.ResponseWriter, r *http.Request) {
 	env := envelope{
 		"status": "available",
@@ -13,8 +20,30 @@ func (a *Server) healthcheckHandler(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
+	if r.URL.Query().Get("verbose") == "true" {
+		report := health.Run(r.Context(), a.checks, checkTimeout)
+		env["status"] = report.Status
+		env["dependencies"] = report.Dependencies
+	}
+
 	err := a.writeJSON(w, http.StatusOK, env)
 	if err != nil {
 		a.serverErrorResponse(w, r, err)
 	}
 }
+
+// readinessHandler actively checks every dependency and responds with a 503 status
+// code when a critical one is unavailable, so no traffic is routed to this instance.
+func (a *Server) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	report := health.Run(r.Context(), a.checks, checkTimeout)
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+
+	err := a.writeJSON(w, status, envelope{"status": report.Status, "dependencies": report.Dependencies})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
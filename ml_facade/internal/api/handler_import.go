@@ -0,0 +1,266 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"ml_facade/internal/csvimport"
+	"ml_facade/internal/service"
+	"ml_facade/internal/validator"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	// importOrigin is the origin of the records stored by the CSV imports.
+	importOrigin = "import"
+	// reasonInvalidRow is the reason of the rows that could not be turned into a reading.
+	reasonInvalidRow = "invalid_row"
+	// reasonProcessingFailed is the reason of the rows whose chunk could not be scored.
+	reasonProcessingFailed = "processing_failed"
+	// maxMappingBytes is the maximum size of the mapping field of a multipart upload.
+	maxMappingBytes = 64 << 10
+)
+
+var (
+	errMissingFile          = errors.New("the multipart form must contain a file field")
+	errUnsupportedMediaType = errors.New("unsupported media type")
+)
+
+// importSummary reports the outcome of a CSV import. Rows are identified by their line
+// in the file, the header being on line 1.
+type importSummary struct {
+	Rows       int               `json:"rows"`
+	Accepted   int               `json:"accepted"`
+	Duplicates []int             `json:"duplicates"`
+	Rejected   []importRejection `json:"rejected"`
+}
+
+type importRejection struct {
+	Row    int    `json:"row"`
+	Reason string `json:"reason"`
+	Detail string `json:"detail"`
+}
+
+// importCSVHandler imports a CSV file of historical readings, such as data/sensor.csv,
+// sent either as the raw body with a text/csv content type or as the file field of a
+// multipart form. The file is read as it is uploaded and its rows are scored in chunks,
+// then stored with the import origin.
+//
+// Columns are mapped to the fields of a reading by name. The mapping query parameter,
+// or the mapping field of a multipart form sent before the file, holds a JSON object
+// mapping other column names to a field, or to "" to ignore them. The machine_id query
+// parameter sets the machine of the rows when no column holds it, and fill_empty the
+// value of the empty sensor cells, which are refused otherwise.
+//
+// Invalid rows are reported in the summary along with their line, without failing the
+// import. The rows read before an upload error are still imported, and reported in the
+// summary sent along with the error.
+func (a *Server) importCSVHandler(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(a.config.Import.Timeout)
+	if err := rc.SetReadDeadline(deadline); err != nil {
+		a.logError(r, err)
+	}
+	if err := rc.SetWriteDeadline(deadline); err != nil {
+		a.logError(r, err)
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, a.config.Import.MaxBodyBytes)
+
+	qs := r.URL.Query()
+	v := validator.New()
+	opts := csvimport.Options{
+		MachineID: a.readOptionalInt(qs, "machine_id", v),
+		FillEmpty: a.readFloat(qs, "fill_empty", v),
+	}
+	if mapping := qs.Get("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &opts.Mapping); err != nil {
+			v.AddError("mapping", "must be a JSON object of column names to field names")
+		}
+	}
+	if opts.MachineID != nil {
+		v.Check(*opts.MachineID >= 0, "machine_id", "must not be negative")
+	}
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	file, err := a.openImportFile(r, &opts)
+	if err != nil {
+		a.importErrorResponse(w, r, err)
+		return
+	}
+
+	reader, err := csvimport.NewReader(file, a.service.Schema(), opts)
+	if err != nil {
+		a.importErrorResponse(w, r, err)
+		return
+	}
+
+	summary, err := a.importRows(r.Context(), reader)
+	if err != nil {
+		a.importFailedResponse(w, r, summary, err)
+		return
+	}
+
+	err = a.writeJSON(w, http.StatusOK, envelope{"import": summary})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// openImportFile returns the CSV file of the request. The mapping of a multipart form
+// is read into opts, replacing the one of the query string.
+func (a *Server) openImportFile(r *http.Request, opts *csvimport.Options) (io.Reader, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return r.Body, nil
+	case "multipart/form-data":
+		mr, err := r.MultipartReader()
+		if err != nil {
+			return nil, err
+		}
+
+		for {
+			part, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				return nil, errMissingFile
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			switch part.FormName() {
+			case "file":
+				return part, nil
+			case "mapping":
+				err := json.NewDecoder(io.LimitReader(part, maxMappingBytes)).Decode(&opts.Mapping)
+				if err != nil {
+					return nil, &csvimport.MappingError{Errors: map[string]string{
+						"mapping": "must be a JSON object of column names to field names",
+					}}
+				}
+			}
+		}
+	default:
+		return nil, errUnsupportedMediaType
+	}
+}
+
+// importRows scores the rows of the file chunk by chunk. When the file cannot be read
+// to its end, the rows read so far are still scored and the summary is returned along
+// with the error.
+func (a *Server) importRows(ctx context.Context, reader *csvimport.Reader) (importSummary, error) {
+	summary := importSummary{Duplicates: []int{}, Rejected: []importRejection{}}
+	chunk := make([]csvimport.Row, 0, a.config.Import.ChunkSize)
+
+	var readErr error
+	for {
+		row, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var rowError *csvimport.RowError
+		if errors.As(err, &rowError) {
+			summary.Rows++
+			summary.Rejected = append(summary.Rejected, importRejection{Row: rowError.Line, Reason: reasonInvalidRow, Detail: rowError.Detail})
+			continue
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+
+		summary.Rows++
+		chunk = append(chunk, row)
+		if len(chunk) == a.config.Import.ChunkSize {
+			a.importChunk(ctx, chunk, &summary)
+			chunk = chunk[:0]
+		}
+	}
+	if len(chunk) > 0 {
+		a.importChunk(ctx, chunk, &summary)
+	}
+
+	sort.SliceStable(summary.Rejected, func(i, j int) bool {
+		return summary.Rejected[i].Row < summary.Rejected[j].Row
+	})
+	sort.Ints(summary.Duplicates)
+	return summary, readErr
+}
+
+// importChunk scores a chunk of rows and adds its outcome to the summary. The rows of a
+// chunk that could not be scored are rejected as a whole.
+func (a *Server) importChunk(ctx context.Context, rows []csvimport.Row, summary *importSummary) {
+	readings := make(service.Readings, len(rows))
+	for i, row := range rows {
+		readings[i] = row.Reading
+	}
+
+	a.wg.Add(1)
+	result, err := a.service.HandleMlServiceRequest(ctx, readings, importOrigin)
+
+	rejected := make(map[int]bool, len(result.Rejected))
+	for _, rejection := range result.Rejected {
+		rejected[rejection.Index] = true
+		summary.Rejected = append(summary.Rejected, importRejection{Row: rows[rejection.Index].Line, Reason: rejection.Reason, Detail: rejection.Detail})
+	}
+
+	if err != nil {
+		a.logger.ErrorContext(ctx, fmt.Sprintf("error importing rows %d to %d: %v", rows[0].Line, rows[len(rows)-1].Line, err))
+		for i, row := range rows {
+			if !rejected[i] {
+				summary.Rejected = append(summary.Rejected, importRejection{Row: row.Line, Reason: reasonProcessingFailed, Detail: err.Error()})
+			}
+		}
+		return
+	}
+
+	for _, duplicate := range result.Duplicates {
+		summary.Duplicates = append(summary.Duplicates, rows[duplicate.Index].Line)
+	}
+	summary.Accepted += len(result.Predictions)
+}
+
+// importFailedResponse writes the response of an import whose file could not be read
+// to its end, holding the summary of the rows read before the error.
+func (a *Server) importFailedResponse(w http.ResponseWriter, r *http.Request, summary importSummary, err error) {
+	status := http.StatusBadRequest
+	message := err.Error()
+
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		status = http.StatusRequestEntityTooLarge
+		message = fmt.Sprintf("body must not be larger than %d bytes", maxBytesError.Limit)
+	}
+
+	err = a.writeJSON(w, status, envelope{"import": summary, "error": message})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// importErrorResponse writes the response of an import that could not be read.
+func (a *Server) importErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	var mappingError *csvimport.MappingError
+	var maxBytesError *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &mappingError):
+		a.failedValidationResponse(w, r, mappingError.Errors)
+	case errors.As(err, &maxBytesError):
+		a.bodyTooLargeResponse(w, r, maxBytesError.Limit)
+	case errors.Is(err, errUnsupportedMediaType):
+		a.unsupportedMediaTypeResponse(w, r, "text/csv", "multipart/form-data")
+	default:
+		a.badRequestResponse(w, r, err)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ml_facade/internal/models/postgres_models"
+	"ml_facade/internal/validator"
+	"net/http"
+	"time"
+)
+
+// jobUploadTimeout replaces the read timeout of the server for the predict job uploads,
+// which are larger than the bodies of the other routes.
+const jobUploadTimeout = time.Minute
+
+// createJobHandler queues a batch of readings to be scored in the background, and answers
+// with status code 202 Accepted and the job to poll. The readings are only checked to be
+// a JSON array here: the readings that do not match the sensor schema are reported with
+// the errors of their chunk once the job runs.
+func (a *Server) createJobHandler(w http.ResponseWriter, r *http.Request) {
+	err := http.NewResponseController(w).SetReadDeadline(time.Now().Add(jobUploadTimeout))
+	if err != nil {
+		a.logError(r, err)
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, a.config.Jobs.MaxBodyBytes)
+
+	var readings []json.RawMessage
+	err = json.NewDecoder(r.Body).Decode(&readings)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			a.bodyTooLargeResponse(w, r, maxBytesError.Limit)
+			return
+		}
+		a.badRequestResponse(w, r, fmt.Errorf("body contains badly-formed JSON: %v", err))
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(readings) > 0, "body", "must contain at least 1 reading")
+	v.Check(len(readings) <= a.config.Jobs.MaxReadings, "body", fmt.Sprintf("must not contain more than %d readings", a.config.Jobs.MaxReadings))
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Chunks go through the same validation as the predict requests
+	chunkSize := min(a.config.Jobs.ChunkSize, a.config.Validation.MaxBatchSize)
+	var apiKeyID *int64
+	if key := a.contextGetApiKey(r); key != nil {
+		apiKeyID = &key.ID
+	}
+	job, err := a.jobModel.Insert(r.Context(), readings, chunkSize, apiKeyID)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/v1/predict/jobs/%d", job.ID))
+	err = a.writeJSON(w, http.StatusAccepted, envelope{"job": job})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// showJobHandler returns the progress of a predict job, the errors of its failed chunks
+// and the predictions of the readings scored so far. Only the key that created the job and
+// the admin keys can read it: the jobs of the other keys are reported as not found.
+func (a *Server) showJobHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	job, err := a.jobModel.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres_models.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !a.ownsJob(r, job) {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	err = a.writeJSON(w, http.StatusOK, envelope{"job": job})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// ownsJob reports whether the key of the request may read a job. Every job can be read
+// when authentication is disabled.
+func (a *Server) ownsJob(r *http.Request, job postgres_models.Job) bool {
+	if !a.authenticator.Enabled() {
+		return true
+	}
+
+	key := a.contextGetApiKey(r)
+	switch {
+	case key == nil:
+		return false
+	case key.Scopes.Include(postgres_models.ScopeAdmin):
+		return true
+	default:
+		return job.ApiKeyID != nil && *job.ApiKeyID == key.ID
+	}
+}
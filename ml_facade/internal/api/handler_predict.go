@@ -1,38 +1,93 @@
 package api
 
 import (
-	"ml_facade/internal/models/postgres_models"
+	"errors"
+	"mime"
+	"ml_facade/internal/codec"
+	"ml_facade/internal/service"
 	"net/http"
 )
 
 // predictHandler handles incoming sensor data, processes it through the ML model,
 // determines anomalies based on reconstruction error and threshold, and stores
-// the results in the database. It writes the reconstruction error and anomaly
-// counter as a JSON response.
+// the results in the database. It writes the prediction of every reading and a
+// summary per machine in the response.
+//
+// The readings are sent as JSON, or in Protobuf or MessagePack with the matching
+// Content-Type, and the response follows the Accept header, JSON being the default.
+//
+// Invalid bodies never reach the ml service: they are refused with field-level
+// errors when a reading does not match the sensor schema. Readings that were already
+// ingested are listed as duplicates instead of being scored again. Bodies holding
+// readings of a machine that exceeded its rate limit are refused with a 429.
+//
+// NDJSON bodies are streamed instead, see predictNDJSONHandler.
 func (a *Server) predictHandler(w http.ResponseWriter, r *http.Request) {
-	var modelResponse postgres_models.MlServiceResponse
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == ndjsonContentType {
+		a.predictNDJSONHandler(w, r)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, a.config.Validation.MaxBodyBytes)
+
+	var body any = r.Body
+	if mediaType := codec.MediaType(r.Header.Get("Content-Type")); mediaType != codec.JSON {
+		body = service.Encoded{MediaType: mediaType, Body: r.Body}
+	}
 
-	a.wg.Add(2)
+	a.wg.Add(1)
 
-	modelResponse, anomalyCounter, err := a.service.HandleMlServiceRequest(r.Body, "api")
+	result, err := a.service.HandleMlServiceRequest(r.Context(), body, "api")
 
 	if err != nil {
-		a.errorResponse(w, r, http.StatusInternalServerError, err.Error())
+		var validationError *service.ValidationError
+		var maxBytesError *http.MaxBytesError
+		var rateLimitError *service.RateLimitError
+
+		switch {
+		case errors.As(err, &validationError):
+			a.failedValidationResponse(w, r, validationError.Errors)
+		case errors.As(err, &maxBytesError):
+			a.bodyTooLargeResponse(w, r, maxBytesError.Limit)
+		case errors.Is(err, service.ErrMalformedBody):
+			a.badRequestResponse(w, r, err)
+		case errors.As(err, &rateLimitError):
+			a.machineRateLimitExceededResponse(w, r, rateLimitError)
+		case errors.Is(err, service.ErrMlServiceUnavailable):
+			a.serviceUnavailableResponse(w, r)
+		default:
+			a.errorResponse(w, r, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
-	a.writeResponse(w, modelResponse.ReconstructionErrors, anomalyCounter)
+	a.wg.Add(1)
+	a.writeResponse(w, r, result)
 }
 
-// writeResponse writes the reconstruction error and anomaly counter as a JSON response with status code 201 Created.
-func (a *Server) writeResponse(w http.ResponseWriter, reconstructionErrors []float64, anomalyCounter int) {
+// writeResponse writes the predictions, machine summaries and duplicates with status code
+// 201 Created, or 200 OK if every reading was a duplicate.
+//
+// The reconstruction_errors and anomaly_counter fields are kept for the clients of the
+// previous response format, anomaly_counter being the counter after the last reading.
+func (a *Server) writeResponse(w http.ResponseWriter, r *http.Request, result service.Result) {
 	defer a.wg.Done()
 
-	headers := make(http.Header)
-	headers.Set("Content-Type", "application/json")
-	response := envelope{"reconstruction_errors": reconstructionErrors, "anomaly_counter": anomalyCounter}
-	err := a.writeJSON(w, http.StatusCreated, response)
+	response := envelope{
+		"predictions":           result.Predictions,
+		"machines":              result.Machines,
+		"reconstruction_errors": result.ModelResponse.ReconstructionErrors,
+		"anomaly_counter":       result.AnomalyCounter,
+		"duplicates":            result.Duplicates,
+	}
+
+	status := http.StatusCreated
+	if len(result.Predictions) == 0 {
+		status = http.StatusOK
+	}
+	err := a.writeNegotiated(w, r, status, response, result.PredictResponse())
 	if err != nil {
-		a.logger.Error(err.Error())
+		a.logger.ErrorContext(r.Context(), err.Error())
 	}
 }
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"ml_facade/internal/service"
+	"net/http"
+	"time"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+// ndjsonResult is the outcome of a reading of an NDJSON predict request. Index is the
+// position of the reading in the request, blank lines aside. Exactly one of the other
+// fields is set: Error is set for the readings whose micro-batch could not be scored.
+type ndjsonResult struct {
+	Index      int                 `json:"index"`
+	Prediction *service.Prediction `json:"prediction,omitempty"`
+	Duplicate  *service.Duplicate  `json:"duplicate,omitempty"`
+	Rejected   *service.Rejection  `json:"rejected,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// predictNDJSONHandler scores a stream of readings, one JSON reading per line, and
+// streams back one result line per reading as soon as its micro-batch is scored, so
+// that the upload can stay open for as long as the client needs.
+//
+// Each line must fit within the maximum body size. Invalid readings are rejected on
+// their own without ending the stream, while an unreadable body ends it with a last
+// line holding only an error.
+func (a *Server) predictNDJSONHandler(w http.ResponseWriter, r *http.Request) {
+	// The stream outlives the timeouts of the server and is read while being answered
+	rc := http.NewResponseController(w)
+	for _, err := range []error{rc.SetReadDeadline(time.Time{}), rc.SetWriteDeadline(time.Time{}), rc.EnableFullDuplex()} {
+		if err != nil {
+			a.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		a.logError(r, err)
+		return
+	}
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(make([]byte, 0, 64<<10), int(a.config.Validation.MaxBodyBytes))
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			select {
+			case lines <- bytes.Clone(line):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	encoder := json.NewEncoder(w)
+	var readings service.Readings
+	var deadline <-chan time.Time
+	offset := 0
+
+	flush := func() error {
+		results := a.predictMicroBatch(r, readings, offset)
+		offset += len(readings)
+		readings = readings[:0]
+		deadline = nil
+
+		for _, result := range results {
+			if err := encoder.Encode(result); err != nil {
+				return err
+			}
+		}
+		return rc.Flush()
+	}
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				if len(readings) > 0 {
+					if err := flush(); err != nil {
+						a.logError(r, err)
+						return
+					}
+				}
+				if err := <-readErr; err != nil {
+					a.logError(r, err)
+					_ = encoder.Encode(envelope{"error": fmt.Sprintf("error reading line %d: %v", offset+1, err)})
+				}
+				return
+			}
+
+			if len(readings) == 0 {
+				deadline = time.After(a.config.ApiServer.NDJSONBatchWait)
+			}
+			readings = append(readings, line)
+			if len(readings) < a.config.ApiServer.NDJSONBatchSize {
+				continue
+			}
+		case <-deadline:
+		case <-r.Context().Done():
+			return
+		}
+
+		if err := flush(); err != nil {
+			a.logError(r, err)
+			return
+		}
+	}
+}
+
+// predictMicroBatch scores a micro-batch of an NDJSON request and returns the result of
+// each of its readings, offset being the position of its first reading in the request.
+func (a *Server) predictMicroBatch(r *http.Request, readings service.Readings, offset int) []ndjsonResult {
+	a.wg.Add(1)
+	result, err := a.service.HandleMlServiceRequest(r.Context(), readings, "api")
+
+	results := make([]ndjsonResult, len(readings))
+	for i := range result.Predictions {
+		prediction := result.Predictions[i]
+		prediction.Index += offset
+		results[prediction.Index-offset].Prediction = &prediction
+	}
+	for i := range result.Duplicates {
+		duplicate := result.Duplicates[i]
+		duplicate.Index += offset
+		results[duplicate.Index-offset].Duplicate = &duplicate
+	}
+	for i := range result.Rejected {
+		rejection := result.Rejected[i]
+		rejection.Index += offset
+		results[rejection.Index-offset].Rejected = &rejection
+	}
+
+	for i := range results {
+		results[i].Index = offset + i
+		if err != nil && results[i].Rejected == nil {
+			results[i].Error = err.Error()
+		}
+	}
+	if err != nil {
+		a.logError(r, err)
+	}
+	return results
+}
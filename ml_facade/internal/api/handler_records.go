@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"ml_facade/internal/models/postgres_models"
+	"ml_facade/internal/validator"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRecordsLimit = 100
+	maxRecordsLimit     = 1000
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+type recordResponse struct {
+	ID                  int64                   `json:"id"`
+	CreatedAt           time.Time               `json:"created_at"`
+	EventTime           time.Time               `json:"event_time"`
+	Late                bool                    `json:"late"`
+	MachineID           int                     `json:"machine_id"`
+	ReconstructionError float64                 `json:"reconstruction_error"`
+	Anomaly             bool                    `json:"anomaly"`
+	AnomalyCounter      int                     `json:"anomaly_counter"`
+	Origin              string                  `json:"origin"`
+	Sensors             *postgres_models.Sensor `json:"sensors,omitempty"`
+}
+
+// listRecordsHandler returns the historical records of a machine, one page at a time.
+//
+// Supported query parameters are from and to (RFC 3339 timestamps), time_field
+// (event_time or created_at), anomaly (boolean), fields (summary or sensors), limit and
+// cursor, the latter being the next_cursor value returned by the previous page.
+//
+// The records are filtered by from and to and ordered on their time_field: event_time,
+// the time the readings were taken at, unless created_at is given to use the time
+// they were stored at, which late and backfilled readings are far from.
+func (a *Server) listRecordsHandler(w http.ResponseWriter, r *http.Request) {
+	machineID, err := a.readMachineIDParam(r)
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	filter := postgres_models.RecordFilter{
+		MachineID: machineID,
+		TimeField: a.readString(qs, "time_field", postgres_models.TimeFieldEventTime),
+		From:      a.readTime(qs, "from", v),
+		To:        a.readTime(qs, "to", v),
+		Anomaly:   a.readBool(qs, "anomaly", v),
+		Limit:     a.readInt(qs, "limit", defaultRecordsLimit, v),
+	}
+
+	fields := a.readString(qs, "fields", "summary")
+	v.Check(validator.PermittedValue(fields, "summary", "sensors"), "fields", "must be either summary or sensors")
+	v.Check(validator.PermittedValue(filter.TimeField, postgres_models.TimeFieldEventTime, postgres_models.TimeFieldCreatedAt),
+		"time_field", "must be either event_time or created_at")
+	filter.WithSensors = fields == "sensors"
+
+	v.Check(filter.Limit > 0, "limit", "must be greater than zero")
+	v.Check(filter.Limit <= maxRecordsLimit, "limit", fmt.Sprintf("must be at most %d", maxRecordsLimit))
+	if filter.From != nil && filter.To != nil {
+		v.Check(filter.From.Before(*filter.To), "to", "must be after from")
+	}
+
+	if cursor := qs.Get("cursor"); cursor != "" {
+		timeField, after, id, err := decodeCursor(cursor)
+		switch {
+		case err != nil:
+			v.AddError("cursor", "must be a cursor returned by a previous page")
+		case timeField != filter.TimeField:
+			v.AddError("cursor", "must be a cursor returned by a previous page with the same time_field")
+		default:
+			filter.AfterTime, filter.AfterID = &after, &id
+		}
+	}
+
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Fetch one more record than requested to know if there is a next page
+	limit := filter.Limit
+	filter.Limit++
+
+	records, err := a.sensorModel.List(r.Context(), filter)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var nextCursor *string
+	if len(records) > limit {
+		records = records[:limit]
+		last := records[len(records)-1]
+		after := last.SensorData.EventTime
+		if filter.TimeField == postgres_models.TimeFieldCreatedAt {
+			after = last.CreatedAt
+		}
+		cursor := encodeCursor(filter.TimeField, after, last.ID)
+		nextCursor = &cursor
+	}
+
+	response := make([]recordResponse, len(records))
+	for i, record := range records {
+		response[i] = recordResponse{
+			ID:                  record.ID,
+			CreatedAt:           record.CreatedAt,
+			EventTime:           record.SensorData.EventTime,
+			Late:                record.Late,
+			MachineID:           record.SensorData.MachineID,
+			ReconstructionError: record.ReconstructionError,
+			Anomaly:             record.Anomaly,
+			AnomalyCounter:      record.AnomalyCounter,
+			Origin:              record.Origin,
+		}
+		if filter.WithSensors {
+			response[i].Sensors = &records[i].SensorData
+		}
+	}
+
+	err = a.writeJSON(w, http.StatusOK, envelope{"records": response, "metadata": envelope{"next_cursor": nextCursor}})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// encodeCursor builds an opaque pagination cursor from the position of a record, the
+// time being the one of the time field the records are ordered on.
+func encodeCursor(timeField string, t time.Time, id int64) string {
+	raw := fmt.Sprintf("%s,%s,%d", timeField, t.UTC().Format(time.RFC3339Nano), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor returns the time field and the record position held by a pagination cursor.
+func decodeCursor(cursor string) (string, time.Time, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", time.Time{}, 0, errInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), ",")
+	if len(parts) != 3 {
+		return "", time.Time{}, 0, errInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return "", time.Time{}, 0, errInvalidCursor
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "", time.Time{}, 0, errInvalidCursor
+	}
+
+	return parts[0], t, id, nil
+}
@@ -0,0 +1,14 @@
+package api
+
+import (
+	"net/http"
+)
+
+// showSchemaHandler returns the sensor schema the readings are parsed with, so that
+// producers know the name, type and unit of every sensor to send.
+func (a *Server) showSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	err := a.writeJSON(w, http.StatusOK, envelope{"schema": a.service.Schema()})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ml_facade/internal/models/postgres_models"
+	"ml_facade/internal/models/redis_models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// resumePageSize is the number of records fetched at once when a stream resumes.
+const resumePageSize = 500
+
+// streamPredictionsHandler streams the predictions of a machine as Server-Sent Events.
+//
+// Every scored reading of the machine is sent as a "prediction" event whose ID is the
+// ID of the stored record. When the client reconnects with a Last-Event-ID header,
+// the records stored in the meantime are replayed from the database first.
+func (a *Server) streamPredictionsHandler(w http.ResponseWriter, r *http.Request) {
+	machineID, err := a.readMachineIDParam(r)
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	var lastEventID *int64
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		id, err := strconv.ParseInt(header, 10, 64)
+		if err != nil {
+			a.badRequestResponse(w, r, errors.New("invalid Last-Event-ID header"))
+			return
+		}
+		lastEventID = &id
+	}
+
+	// The stream outlives the write timeout of the server
+	rc := http.NewResponseController(w)
+	err = rc.SetWriteDeadline(time.Time{})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Subscribe before replaying the stored records so no event is lost in between
+	events, err := a.predictionModel.Subscribe(r.Context(), machineID)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// The events published while the stored records are replayed are sent once
+	var replayed map[int64]bool
+	if lastEventID != nil {
+		replayed, err = a.resumeStream(r.Context(), w, machineID, *lastEventID)
+		if err != nil {
+			a.logError(r, err)
+			return
+		}
+	}
+
+	err = rc.Flush()
+	if err != nil {
+		a.logError(r, err)
+		return
+	}
+
+	heartbeat := time.NewTicker(a.config.ApiServer.StreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			// Records are published in commit order rather than in ID order, so only
+			// the records already replayed are skipped
+			if replayed[event.ID] {
+				delete(replayed, event.ID)
+				continue
+			}
+			err = writeEvent(w, event)
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		case <-r.Context().Done():
+			return
+		case <-a.shutdown:
+			return
+		}
+
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			a.logError(r, err)
+			return
+		}
+	}
+}
+
+// resumeStream writes the records of the machine stored after lastEventID and
+// returns the IDs of the records written.
+func (a *Server) resumeStream(ctx context.Context, w http.ResponseWriter, machineID int, lastEventID int64) (map[int64]bool, error) {
+	filter := postgres_models.RecordFilter{
+		MachineID: machineID,
+		TimeField: postgres_models.TimeFieldCreatedAt,
+		SinceID:   &lastEventID,
+		Limit:     resumePageSize,
+	}
+
+	written := make(map[int64]bool)
+	for {
+		records, err := a.sensorModel.List(ctx, filter)
+		if err != nil {
+			return written, err
+		}
+
+		for _, record := range records {
+			err := writeEvent(w, redis_models.PredictionEvent{
+				ID:                  record.ID,
+				CreatedAt:           record.CreatedAt,
+				EventTime:           record.SensorData.EventTime,
+				Late:                record.Late,
+				MachineID:           record.SensorData.MachineID,
+				ReconstructionError: record.ReconstructionError,
+				Anomaly:             record.Anomaly,
+				AnomalyCounter:      record.AnomalyCounter,
+				Origin:              record.Origin,
+			})
+			if err != nil {
+				return written, err
+			}
+			written[record.ID] = true
+		}
+
+		if len(records) < resumePageSize {
+			return written, nil
+		}
+
+		last := records[len(records)-1]
+		filter.AfterTime, filter.AfterID = &last.CreatedAt, &last.ID
+	}
+}
+
+// writeEvent writes a prediction as a Server-Sent Event.
+func writeEvent(w http.ResponseWriter, event redis_models.PredictionEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: prediction\ndata: %s\n\n", event.ID, data)
+	return err
+}
@@ -2,28 +2,107 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"ml_facade/internal/models/redis_models"
 	"net/http"
 )
 
 // thresholdHandler handles incoming threshold data, inserts it into the database, and returns a success response.
+//
+// The anomaly counter of the machine is kept unless the request explicitly asks to reset it.
 func (a *Server) thresholdHandler(w http.ResponseWriter, r *http.Request) {
-	var input redis_models.Threshold
+	var input struct {
+		MachineID    int     `json:"machine_id"`
+		Threshold    float64 `json:"threshold"`
+		ResetCounter bool    `json:"reset_counter"`
+	}
 
 	err := json.NewDecoder(r.Body).Decode(&input)
 	if err != nil {
-		a.logger.Error(err.Error())
+		a.logger.ErrorContext(r.Context(), err.Error())
 		a.errorResponse(w, r, http.StatusBadRequest, "invalid JSON in request body")
 		return
 	}
 
-	err = a.redisModel.Insert(input)
+	threshold := redis_models.Threshold{
+		MachineID: input.MachineID,
+		Threshold: input.Threshold,
+	}
+
+	err = a.redisModel.Insert(r.Context(), threshold, input.ResetCounter)
 	if err != nil {
 		a.serverErrorResponse(w, r, err)
 		return
 	}
+	a.service.InvalidateThreshold(input.MachineID)
+
 	err = a.writeJSON(w, http.StatusCreated, envelope{"threshold": input.Threshold})
 	if err != nil {
-		a.logger.Error(err.Error())
+		a.logger.ErrorContext(r.Context(), err.Error())
+	}
+}
+
+// listThresholdsHandler returns the threshold and anomaly counter of every configured machine.
+func (a *Server) listThresholdsHandler(w http.ResponseWriter, r *http.Request) {
+	thresholds, err := a.redisModel.List(r.Context())
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = a.writeJSON(w, http.StatusOK, envelope{"thresholds": thresholds})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// showThresholdHandler returns the threshold and anomaly counter of a single machine.
+func (a *Server) showThresholdHandler(w http.ResponseWriter, r *http.Request) {
+	machineID, err := a.readMachineIDParam(r)
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	threshold, err := a.redisModel.GetWithCounter(r.Context(), machineID)
+	if err != nil {
+		switch {
+		case errors.Is(err, redis_models.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = a.writeJSON(w, http.StatusOK, envelope{"threshold": threshold})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteThresholdHandler removes the threshold and the anomaly counter of a machine.
+func (a *Server) deleteThresholdHandler(w http.ResponseWriter, r *http.Request) {
+	machineID, err := a.readMachineIDParam(r)
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = a.redisModel.Delete(r.Context(), machineID)
+	if err != nil {
+		switch {
+		case errors.Is(err, redis_models.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	a.service.InvalidateThreshold(machineID)
+
+	err = a.writeJSON(w, http.StatusOK, envelope{"message": "threshold successfully deleted"})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
 	}
 }
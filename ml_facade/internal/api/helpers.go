@@ -2,7 +2,14 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"math"
+	"ml_facade/internal/validator"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 )
 
 type envelope map[string]any
@@ -22,3 +29,116 @@ func (a *Server) writeJSON(w http.ResponseWriter, status int, data envelope) err
 
 	return nil
 }
+
+// readMachineIDParam reads the machine_id parameter from the request URL.
+func (a *Server) readMachineIDParam(r *http.Request) (int, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("machine_id"))
+	if err != nil || id < 0 {
+		return 0, errors.New("invalid machine_id parameter")
+	}
+
+	return id, nil
+}
+
+// readIDParam reads the id parameter from the request URL.
+func (a *Server) readIDParam(r *http.Request) (int64, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("invalid id parameter")
+	}
+
+	return id, nil
+}
+
+// readString returns a string value from the query string, or the default value if none is provided.
+func (a *Server) readString(qs url.Values, key string, defaultValue string) string {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	return s
+}
+
+// readInt returns an integer value from the query string, or the default value if none is provided.
+// An error is recorded in the validator if the value cannot be converted.
+func (a *Server) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return defaultValue
+	}
+	return i
+}
+
+// readOptionalInt returns an optional integer value from the query string.
+// An error is recorded in the validator if the value cannot be converted.
+func (a *Server) readOptionalInt(qs url.Values, key string, v *validator.Validator) *int {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return nil
+	}
+	return &i
+}
+
+// readFloat returns an optional float value from the query string.
+// An error is recorded in the validator if the value cannot be converted.
+func (a *Server) readFloat(qs url.Values, key string, v *validator.Validator) *float64 {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		v.AddError(key, "must be a finite number")
+		return nil
+	}
+	return &f
+}
+
+// readBool returns an optional boolean value from the query string.
+// An error is recorded in the validator if the value cannot be converted.
+func (a *Server) readBool(qs url.Values, key string, v *validator.Validator) *bool {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return nil
+	}
+	return &b
+}
+
+// readTime returns an optional RFC 3339 timestamp from the query string.
+// An error is recorded in the validator if the value cannot be parsed.
+func (a *Server) readTime(qs url.Values, key string, v *validator.Validator) *time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		v.AddError(key, "must be a RFC 3339 timestamp")
+		return nil
+	}
+	return &t
+}
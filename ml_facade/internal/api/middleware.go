@@ -1,11 +1,81 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"golang.org/x/time/rate"
+	"io"
+	"mime"
+	"ml_facade/internal/auth"
+	"ml_facade/internal/logging"
+	"ml_facade/internal/metrics"
+	"ml_facade/internal/models/redis_models"
+	"ml_facade/internal/ratelimit"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// maxRequestIDLength is the maximum length of an X-Request-ID header kept as the ID
+// of the request.
+const maxRequestIDLength = 128
+
+// requestID gives every request an ID, the one sent by the client in an X-Request-ID
+// header or a new one, and writes it back in the X-Request-ID header of the response.
+// The ID is added to the log lines of the request and sent to the ml service.
+func (a *Server) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = logging.NewID()
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID reports whether an ID sent by a client can be logged as is.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// logAccess logs a line per request once it is handled, with its status code, its
+// latency, the size of the response body and the IP address of the client.
+func (a *Server) logAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		a.logger.InfoContext(r.Context(), "request",
+			"method", r.Method,
+			"uri", r.URL.RequestURI(),
+			"status", recorder.status,
+			"latency_ms", float64(time.Since(start).Microseconds())/1000,
+			"bytes", recorder.bytes,
+			"client", clientIP(r),
+		)
+	})
+}
+
 func (a *Server) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
@@ -19,20 +89,329 @@ func (a *Server) recoverPanic(next http.Handler) http.Handler {
 	})
 }
 
-func (a *Server) rateLimit(next http.Handler) http.Handler {
-	limiter := rate.NewLimiter(rate.Limit(a.config.ApiServer.Limiter.Rps), a.config.ApiServer.Limiter.Burst)
+// rateLimitIP limits the requests of every IP address with the IP rate limiter, whatever
+// their API key. It runs before authenticate, so that the requests with an invalid API
+// key are limited too, without looking the key up. Its rate being larger than the one
+// of the clients, the API keys used behind a NAT do not starve each other.
+func (a *Server) rateLimitIP(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if a.config.ApiServer.Limiter.Enabled {
-			if r.URL.Path == "/health" {
-				next.ServeHTTP(w, r)
-				return
-			}
-			if !limiter.Allow() {
-				a.rateLimitExceededResponse(w, r)
-				return
+		if a.limitRequest(w, r, a.ipLimiter, "ip", clientIP(r)) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// rateLimitClient limits the requests of every client once authenticate identified it:
+// by its API key, whatever the IP addresses it is sent from, and by its IP address for
+// the anonymous requests. The responses tell the client about its limit in the
+// X-RateLimit-* headers, and when to retry in a Retry-After header once it is limited.
+func (a *Server) rateLimitClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.limitRequest(w, r, a.limiter, "client", a.clientKey(r)) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// limitRequest spends a token of the client's bucket in the limiter and sets the
+// X-RateLimit-* headers. It reports whether the request may go on, writing the response
+// otherwise.
+func (a *Server) limitRequest(w http.ResponseWriter, r *http.Request, limiter *ratelimit.Limiter, name, client string) bool {
+	if limiter == nil || r.URL.Path == "/health" || r.URL.Path == "/ready" {
+		return true
+	}
+
+	decision := limiter.Allow(r.Context(), client)
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ratelimit.Seconds(decision.Reset)))
+	if !decision.Allowed {
+		metrics.RateLimited.WithLabelValues(name).Inc()
+		a.rateLimitExceededResponse(w, r, ratelimit.Seconds(decision.RetryAfter))
+		return false
+	}
+	return true
+}
+
+// clientKey identifies the client of a request by its API key, or by its IP address
+// for the anonymous requests.
+func (a *Server) clientKey(r *http.Request) string {
+	if key := a.contextGetApiKey(r); key != nil {
+		return fmt.Sprintf("key:%d", key.ID)
+	}
+	return "ip:" + clientIP(r)
+}
+
+// clientIP returns the IP address the request was sent from.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// authenticate authenticates the requests sent with an API key, in an Authorization
+// header holding a bearer token or in an X-API-Key header. Requests without a key go
+// through anonymously and are refused by requireScope.
+func (a *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.authenticator.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Authorization")
+
+		plaintext, err := auth.KeyFromHeaders(r.Header.Get("Authorization"), r.Header.Get("X-API-Key"))
+		if err != nil {
+			a.invalidApiKeyResponse(w, r)
+			return
+		}
+		if plaintext == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, err := a.authenticator.Authenticate(r.Context(), plaintext)
+		if err != nil {
+			switch {
+			case errors.Is(err, auth.ErrInvalidKey):
+				a.invalidApiKeyResponse(w, r)
+			default:
+				a.serverErrorResponse(w, r, err)
 			}
+			return
+		}
+		a.authenticator.LogUse(r.Context(), key, r.Method, r.URL.Path)
+
+		next.ServeHTTP(w, a.contextSetApiKey(r, &key))
+	})
+}
+
+// requireScope refuses the requests whose API key was not granted the given scope.
+func (a *Server) requireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.authenticator.Enabled() {
+			next(w, r)
+			return
+		}
+
+		key := a.contextGetApiKey(r)
+		switch {
+		case key == nil:
+			a.authenticationRequiredResponse(w, r)
+		case !key.Scopes.Include(scope):
+			a.notPermittedResponse(w, r, scope)
+		default:
+			next(w, r)
+		}
+	}
+}
+
+// statusRecorder records the status code and the number of bytes written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying ResponseWriter.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// instrument records the number and the latency of the requests handled by every route,
+// including the requests that panicked. The route of a request is set by route once the
+// request is matched, the unmatched requests not being recorded.
+func (a *Server) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		route := new(string)
+
+		next.ServeHTTP(recorder, a.contextSetRoute(r, route))
+
+		if *route == "" {
+			return
+		}
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
 		}
+		metrics.HttpRequests.WithLabelValues(r.Method, *route, strconv.Itoa(recorder.status)).Inc()
+		metrics.HttpRequestDuration.WithLabelValues(r.Method, *route).Observe(time.Since(start).Seconds())
+	})
+}
 
+// route labels the requests of a route for instrument.
+func (a *Server) route(path string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := a.contextGetRoute(r); route != nil {
+			*route = path
+		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// idempotencyLockTimeout bounds how long an idempotency key stays locked by a request
+// that never completes, for instance because the replica handling it crashed.
+const idempotencyLockTimeout = time.Minute
+
+// maxIdempotencyKeyLength is the maximum length, in bytes, of an Idempotency-Key header.
+const maxIdempotencyKeyLength = 255
+
+// errIdempotencyKeyStreamed is returned when an Idempotency-Key is sent with a streamed
+// NDJSON request.
+var errIdempotencyKeyStreamed = errors.New("the Idempotency-Key header is not supported with application/x-ndjson bodies, whose results are streamed")
+
+// responseCapture records the status code and the body written by a handler, while
+// still writing them to the client.
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying ResponseWriter.
+func (c *responseCapture) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// idempotent replays the original response of the requests sent again with the same
+// Idempotency-Key header, instead of processing them twice.
+//
+// The key is bound to the request body and to its Content-Type and Accept headers:
+// reusing it with another body is refused, and so is a request sent while another one
+// with the same key is being processed. Server errors are not stored, which lets the
+// client retry the request with the same key.
+//
+// Keys are scoped to the client, identified by clientKey: two clients using the same
+// key never see each other's responses.
+//
+// The key is refused on NDJSON requests: their response is streamed as the readings are
+// scored, so it can neither be stored nor replayed.
+func (a *Server) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || a.config.ApiServer.IdempotencyWindow <= 0 {
+			next(w, r)
+			return
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == ndjsonContentType {
+			a.badRequestResponse(w, r, errIdempotencyKeyStreamed)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			message := fmt.Sprintf("must not be more than %d bytes long", maxIdempotencyKeyLength)
+			a.failedValidationResponse(w, r, map[string]string{"Idempotency-Key": message})
+			return
+		}
+
+		// The body is read beforehand to fingerprint the request
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, a.config.Validation.MaxBodyBytes))
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				a.bodyTooLargeResponse(w, r, maxBytesError.Limit)
+				return
+			}
+			a.badRequestResponse(w, r, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+		// The same bytes hold other readings in another format, and get another response
+		hash.Write([]byte(r.Header.Get("Content-Type") + "\n" + r.Header.Get("Accept") + "\n"))
+		hash.Write(body)
+		fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+		key = a.clientKey(r) + ":" + key
+
+		stored, err := a.idempotencyModel.Begin(r.Context(), key, fingerprint, idempotencyLockTimeout)
+		switch {
+		case errors.Is(err, redis_models.ErrIdempotencyInProgress):
+			a.idempotencyConflictResponse(w, r)
+			return
+		case err != nil:
+			a.serverErrorResponse(w, r, err)
+			return
+		case stored != nil && stored.Fingerprint != fingerprint:
+			a.idempotencyMismatchResponse(w, r)
+			return
+		case stored != nil:
+			metrics.IdempotentReplays.Inc()
+			w.Header().Set("Content-Type", stored.ContentType)
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			_, err = w.Write(stored.Body)
+			if err != nil {
+				a.logError(r, err)
+			}
+			return
+		}
+
+		capture := &responseCapture{ResponseWriter: w}
+		returned := false
+
+		// The key is released or completed even if the client went away in the meantime,
+		// and released when the handler panics, recoverPanic answering further out
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), idempotencyLockTimeout)
+			defer cancel()
+
+			var err error
+			if !returned || capture.status == 0 || capture.status >= http.StatusInternalServerError {
+				err = a.idempotencyModel.Abandon(ctx, key)
+			} else {
+				err = a.idempotencyModel.Complete(ctx, key, redis_models.IdempotentResponse{
+					Fingerprint: fingerprint,
+					Status:      capture.status,
+					ContentType: capture.Header().Get("Content-Type"),
+					Body:        capture.body.Bytes(),
+				}, a.config.ApiServer.IdempotencyWindow)
+			}
+			if err != nil {
+				a.logError(r, err)
+			}
+		}()
+
+		next(capture, r)
+		returned = true
+	}
+}
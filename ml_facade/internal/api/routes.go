@@ -1,6 +1,8 @@
 package api
 
 import (
+	"ml_facade/internal/metrics"
+	"ml_facade/internal/models/postgres_models"
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
@@ -11,9 +13,29 @@ func (a *Server) routes() http.Handler {
 	router.NotFound = http.HandlerFunc(a.notFoundResponse)
 	router.MethodNotAllowed = http.HandlerFunc(a.methodNotAllowedResponse)
 
-	router.HandlerFunc(http.MethodGet, "/health", a.healthcheckHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/predict", a.predictHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/threshold", a.thresholdHandler)
+	handle := func(method, path string, handler http.HandlerFunc) {
+		router.Handler(method, path, a.route(path, handler))
+	}
 
-	return a.recoverPanic(a.rateLimit(router))
+	handle(http.MethodGet, "/health", a.healthcheckHandler)
+	handle(http.MethodGet, "/ready", a.readinessHandler)
+	handle(http.MethodGet, "/v1/schema", a.showSchemaHandler)
+	handle(http.MethodPost, "/v1/predict", a.requireScope(postgres_models.ScopePredictWrite, a.idempotent(a.predictHandler)))
+	handle(http.MethodPost, "/v1/predict/jobs", a.requireScope(postgres_models.ScopePredictWrite, a.createJobHandler))
+	handle(http.MethodGet, "/v1/predict/jobs/:id", a.requireScope(postgres_models.ScopePredictRead, a.showJobHandler))
+	handle(http.MethodPost, "/v1/import/csv", a.requireScope(postgres_models.ScopePredictWrite, a.importCSVHandler))
+	handle(http.MethodPost, "/v1/threshold", a.requireScope(postgres_models.ScopeThresholdWrite, a.thresholdHandler))
+	handle(http.MethodGet, "/v1/thresholds", a.requireScope(postgres_models.ScopeRecordsRead, a.listThresholdsHandler))
+	handle(http.MethodGet, "/v1/thresholds/:machine_id", a.requireScope(postgres_models.ScopeRecordsRead, a.showThresholdHandler))
+	handle(http.MethodDelete, "/v1/thresholds/:machine_id", a.requireScope(postgres_models.ScopeThresholdWrite, a.deleteThresholdHandler))
+	handle(http.MethodGet, "/v1/machines/:machine_id/records", a.requireScope(postgres_models.ScopeRecordsRead, a.listRecordsHandler))
+	handle(http.MethodGet, "/v1/machines/:machine_id/stream", a.requireScope(postgres_models.ScopeRecordsRead, a.streamPredictionsHandler))
+	handle(http.MethodPost, "/v1/api-keys", a.requireScope(postgres_models.ScopeAdmin, a.createApiKeyHandler))
+	handle(http.MethodGet, "/v1/api-keys", a.requireScope(postgres_models.ScopeAdmin, a.listApiKeysHandler))
+	handle(http.MethodDelete, "/v1/api-keys/:id", a.requireScope(postgres_models.ScopeAdmin, a.revokeApiKeyHandler))
+
+	// Scrapers authenticate with a key holding the metrics:read scope, sent as a bearer token
+	router.Handler(http.MethodGet, "/metrics", a.requireScope(postgres_models.ScopeMetricsRead, metrics.Handler().ServeHTTP))
+
+	return a.requestID(a.logAccess(a.instrument(a.recoverPanic(a.rateLimitIP(a.authenticate(a.rateLimitClient(router)))))))
 }
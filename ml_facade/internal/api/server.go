@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"log/slog"
 	"ml_facade/config"
+	"ml_facade/internal/auth"
+	"ml_facade/internal/health"
+	"ml_facade/internal/models/postgres_models"
 	"ml_facade/internal/models/redis_models"
+	"ml_facade/internal/ratelimit"
 	"ml_facade/internal/service"
 	"net/http"
 	"sync"
@@ -14,22 +18,57 @@ import (
 )
 
 type Server struct {
-	config     config.Config
-	logger     *slog.Logger
-	service    *service.MlService
-	redisModel *redis_models.ThresholdModel
-	version    string
-	wg         *sync.WaitGroup
+	config           config.Config
+	logger           *slog.Logger
+	service          *service.MlService
+	redisModel       *redis_models.ThresholdModel
+	sensorModel      *postgres_models.SensorModel
+	predictionModel  *redis_models.PredictionModel
+	idempotencyModel *redis_models.IdempotencyModel
+	jobModel         *postgres_models.JobModel
+	apiKeyModel      *postgres_models.ApiKeyModel
+	authenticator    *auth.Authenticator
+	limiter          *ratelimit.Limiter
+	ipLimiter        *ratelimit.Limiter
+	checks           []health.Check
+	version          string
+	wg               *sync.WaitGroup
+	shutdown         chan struct{}
 }
 
-func NewApiServer(config config.Config, logger *slog.Logger, service *service.MlService, redisModel *redis_models.ThresholdModel, version string, wg *sync.WaitGroup) *Server {
+func NewApiServer(
+	config config.Config,
+	logger *slog.Logger,
+	service *service.MlService,
+	redisModel *redis_models.ThresholdModel,
+	sensorModel *postgres_models.SensorModel,
+	predictionModel *redis_models.PredictionModel,
+	idempotencyModel *redis_models.IdempotencyModel,
+	jobModel *postgres_models.JobModel,
+	apiKeyModel *postgres_models.ApiKeyModel,
+	authenticator *auth.Authenticator,
+	limiter *ratelimit.Limiter,
+	ipLimiter *ratelimit.Limiter,
+	checks []health.Check,
+	version string,
+	wg *sync.WaitGroup) *Server {
 	return &Server{
-		config:     config,
-		logger:     logger,
-		service:    service,
-		redisModel: redisModel,
-		version:    version,
-		wg:         wg,
+		config:           config,
+		logger:           logger,
+		service:          service,
+		redisModel:       redisModel,
+		sensorModel:      sensorModel,
+		predictionModel:  predictionModel,
+		idempotencyModel: idempotencyModel,
+		jobModel:         jobModel,
+		apiKeyModel:      apiKeyModel,
+		authenticator:    authenticator,
+		limiter:          limiter,
+		ipLimiter:        ipLimiter,
+		checks:           checks,
+		version:          version,
+		wg:               wg,
+		shutdown:         make(chan struct{}),
 	}
 }
 
@@ -43,10 +82,15 @@ func (a *Server) Serve(ctx context.Context) error {
 		ErrorLog:     slog.NewLogLogger(a.logger.Handler(), slog.LevelError),
 	}
 
+	// Long-lived streams never become idle, so they are told to end when the shutdown starts
+	srv.RegisterOnShutdown(func() {
+		close(a.shutdown)
+	})
+
 	go func() {
 		a.logger.Info("starting api server", "addr", srv.Addr)
 		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			a.logger.Error("error api server: %v", err)
+			a.logger.Error(fmt.Sprintf("error api server: %v", err))
 		}
 	}()
 
@@ -0,0 +1,132 @@
+// Package auth authenticates the clients of the HTTP and gRPC APIs with their API key.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"ml_facade/config"
+	"ml_facade/internal/models/postgres_models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidKey is returned for a key that does not exist, expired or was revoked.
+var ErrInvalidKey = errors.New("invalid or expired api key")
+
+// cacheTTL is how long an authenticated key is trusted before being looked up again,
+// which bounds how long a revoked key keeps working on the other replicas.
+const cacheTTL = 30 * time.Second
+
+// KeyStore looks up the stored API keys.
+type KeyStore interface {
+	GetForKey(ctx context.Context, plaintext string) (postgres_models.ApiKey, error)
+}
+
+type cachedKey struct {
+	key       postgres_models.ApiKey
+	expiresAt time.Time
+}
+
+// Authenticator checks the API keys sent by the clients. The bootstrap admin key of
+// the configuration is checked first, the other keys are looked up in the store and
+// cached for cacheTTL.
+type Authenticator struct {
+	config   config.CfgAuth
+	logger   *slog.Logger
+	store    KeyStore
+	adminKey []byte
+
+	mu    sync.Mutex
+	cache map[string]cachedKey
+}
+
+func NewAuthenticator(cfg config.CfgAuth, logger *slog.Logger, store KeyStore) *Authenticator {
+	a := &Authenticator{
+		config: cfg,
+		logger: logger,
+		store:  store,
+		cache:  make(map[string]cachedKey),
+	}
+	if cfg.AdminKey != "" {
+		a.adminKey = postgres_models.HashApiKey(cfg.AdminKey)
+	}
+	return a
+}
+
+// Enabled reports whether the clients must be authenticated.
+func (a *Authenticator) Enabled() bool {
+	return a.config.Enabled
+}
+
+// Authenticate returns the key matching a plaintext key, or ErrInvalidKey. The
+// bootstrap admin key is returned with the ID 0.
+func (a *Authenticator) Authenticate(ctx context.Context, plaintext string) (postgres_models.ApiKey, error) {
+	hash := postgres_models.HashApiKey(plaintext)
+	if a.adminKey != nil && subtle.ConstantTimeCompare(hash, a.adminKey) == 1 {
+		return postgres_models.ApiKey{Name: "admin", Scopes: postgres_models.Scopes{postgres_models.ScopeAdmin}}, nil
+	}
+
+	now := time.Now()
+	a.mu.Lock()
+	cached, ok := a.cache[string(hash)]
+	a.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) && (cached.key.ExpiresAt == nil || now.Before(*cached.key.ExpiresAt)) {
+		return cached.key, nil
+	}
+
+	key, err := a.store.GetForKey(ctx, plaintext)
+	if errors.Is(err, postgres_models.ErrRecordNotFound) {
+		a.forget(string(hash))
+		return postgres_models.ApiKey{}, ErrInvalidKey
+	}
+	if err != nil {
+		return postgres_models.ApiKey{}, err
+	}
+
+	a.mu.Lock()
+	a.cache[string(hash)] = cachedKey{key: key, expiresAt: now.Add(cacheTTL)}
+	a.mu.Unlock()
+	return key, nil
+}
+
+// Revoked drops a revoked key from the cache, so that it stops working right away on
+// this replica.
+func (a *Authenticator) Revoked(id int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for hash, cached := range a.cache {
+		if cached.key.ID == id {
+			delete(a.cache, hash)
+		}
+	}
+}
+
+// LogUse logs a request authenticated with a key.
+func (a *Authenticator) LogUse(ctx context.Context, key postgres_models.ApiKey, method, target string) {
+	a.logger.InfoContext(ctx, "api key used", "key_id", key.ID, "key_name", key.Name, "method", method, "target", target)
+}
+
+func (a *Authenticator) forget(hash string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.cache, hash)
+}
+
+// KeyFromHeaders returns the key sent as a bearer token in an Authorization header, or
+// in an X-API-Key header. The key is empty if the client sent none, and ErrInvalidKey
+// is returned for an Authorization header that does not hold a bearer token.
+func KeyFromHeaders(authorization, apiKey string) (string, error) {
+	if authorization == "" {
+		return apiKey, nil
+	}
+
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", ErrInvalidKey
+	}
+	return token, nil
+}
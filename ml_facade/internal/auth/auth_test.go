@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"ml_facade/config"
+	"ml_facade/internal/models/postgres_models"
+	"testing"
+)
+
+// fakeStore holds a single key and counts the lookups.
+type fakeStore struct {
+	plaintext string
+	key       postgres_models.ApiKey
+	lookups   int
+}
+
+func (f *fakeStore) GetForKey(_ context.Context, plaintext string) (postgres_models.ApiKey, error) {
+	f.lookups++
+	if plaintext != f.plaintext {
+		return postgres_models.ApiKey{}, postgres_models.ErrRecordNotFound
+	}
+	return f.key, nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	newAuthenticator := func() (*Authenticator, *fakeStore) {
+		store := &fakeStore{
+			plaintext: "stored-key",
+			key:       postgres_models.ApiKey{ID: 1, Name: "gateway", Scopes: postgres_models.Scopes{postgres_models.ScopePredictWrite}},
+		}
+		return NewAuthenticator(config.CfgAuth{Enabled: true, AdminKey: "admin-key"}, logger, store), store
+	}
+
+	// Test Case 1: The bootstrap admin key is granted every scope without a lookup
+	t.Run("Admin Key", func(t *testing.T) {
+		// Arrange
+		a, store := newAuthenticator()
+
+		// Act
+		key, err := a.Authenticate(context.Background(), "admin-key")
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !key.Scopes.Include(postgres_models.ScopeThresholdWrite) {
+			t.Errorf("expected the admin key to be granted every scope, got %v", key.Scopes)
+		}
+		if store.lookups != 0 {
+			t.Errorf("expected no lookup, got %d", store.lookups)
+		}
+	})
+
+	// Test Case 2: Stored keys are cached until they are revoked
+	t.Run("Stored Key", func(t *testing.T) {
+		// Arrange
+		a, store := newAuthenticator()
+
+		// Act
+		first, err := a.Authenticate(context.Background(), "stored-key")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		_, _ = a.Authenticate(context.Background(), "stored-key")
+		lookups := store.lookups
+		a.Revoked(first.ID)
+		_, _ = a.Authenticate(context.Background(), "stored-key")
+
+		// Assert
+		if first.ID != 1 || first.Scopes.Include(postgres_models.ScopeThresholdWrite) {
+			t.Errorf("expected key 1 with the predict:write scope only, got %+v", first)
+		}
+		if lookups != 1 {
+			t.Errorf("expected a single lookup before the revocation, got %d", lookups)
+		}
+		if store.lookups != 2 {
+			t.Errorf("expected the key to be looked up again after the revocation, got %d lookups", store.lookups)
+		}
+	})
+
+	// Test Case 3: Unknown keys are refused
+	t.Run("Unknown Key", func(t *testing.T) {
+		// Arrange
+		a, _ := newAuthenticator()
+
+		// Act
+		_, err := a.Authenticate(context.Background(), "unknown-key")
+
+		// Assert
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("expected ErrInvalidKey, got %v", err)
+		}
+	})
+}
+
+func TestKeyFromHeaders(t *testing.T) {
+	tests := map[string]struct {
+		authorization string
+		apiKey        string
+		expected      string
+		err           error
+	}{
+		"no key":        {"", "", "", nil},
+		"bearer token":  {"Bearer abc", "", "abc", nil},
+		"x-api-key":     {"", "abc", "abc", nil},
+		"basic scheme":  {"Basic abc", "", "", ErrInvalidKey},
+		"missing token": {"Bearer", "", "", ErrInvalidKey},
+	}
+
+	for name, tt := range tests {
+		// Act
+		key, err := KeyFromHeaders(tt.authorization, tt.apiKey)
+
+		// Assert
+		if key != tt.expected || !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %q and %v, got %q and %v", name, tt.expected, tt.err, key, err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Package codec decodes the readings and encodes the responses in the binary formats
+// supported next to JSON: Protocol Buffers, following the schema published in
+// proto/predict/v1, and MessagePack, which mirrors the JSON format.
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"ml_facade/internal/pb/predictv1"
+	"ml_facade/internal/schema"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// Media types of the supported formats.
+const (
+	JSON     = "application/json"
+	Protobuf = "application/x-protobuf"
+	MsgPack  = "application/msgpack"
+)
+
+// aliases maps the other media types in use for the binary formats.
+var aliases = map[string]string{
+	"application/protobuf":    Protobuf,
+	"application/x-msgpack":   MsgPack,
+	"application/vnd.msgpack": MsgPack,
+}
+
+// MediaType returns the format of a body from its Content-Type. JSON is returned for
+// every type that is not a binary format, the content type being empty or wrong for
+// many clients of the JSON format.
+func MediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return JSON
+	}
+	if alias, ok := aliases[mediaType]; ok {
+		mediaType = alias
+	}
+
+	switch mediaType {
+	case Protobuf, MsgPack:
+		return mediaType
+	default:
+		return JSON
+	}
+}
+
+// Negotiate returns the format of the response from the Accept header of a request.
+// The media range with the highest quality wins, the first one on a tie. JSON is
+// returned when the header is empty, accepts anything or lists no supported format.
+func Negotiate(accept string) string {
+	best, bestQuality := JSON, 0.0
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		if alias, ok := aliases[mediaType]; ok {
+			mediaType = alias
+		}
+		switch mediaType {
+		case JSON, Protobuf, MsgPack:
+		case "*/*", "application/*":
+			mediaType = JSON
+		default:
+			continue
+		}
+
+		if quality > bestQuality {
+			best, bestQuality = mediaType, quality
+		}
+	}
+	return best
+}
+
+// DecodeBatch decodes the readings of a predict request: a PredictRequest message in
+// Protobuf, an array of readings in MessagePack. Each reading is returned as a map of
+// its fields, to be validated with schema.DecodeMap.
+func DecodeBatch(mediaType string, data []byte, s *schema.Schema) ([]map[string]any, error) {
+	switch mediaType {
+	case Protobuf:
+		var request predictv1.PredictRequest
+		err := proto.Unmarshal(data, &request)
+		if err != nil {
+			return nil, err
+		}
+		return RequestFields(&request, s)
+	case MsgPack:
+		var readings []map[string]any
+		err := unmarshalMsgPack(data, &readings)
+		if err != nil {
+			return nil, err
+		}
+		for i, reading := range readings {
+			if reading == nil {
+				return nil, fmt.Errorf("reading %d: reading must be a map", i)
+			}
+		}
+		return readings, nil
+	default:
+		return nil, fmt.Errorf("unsupported media type %q", mediaType)
+	}
+}
+
+// DecodeReading decodes a single reading, as published on the AMQP queue: a Reading
+// message in Protobuf, a map in MessagePack.
+func DecodeReading(mediaType string, data []byte, s *schema.Schema) (map[string]any, error) {
+	switch mediaType {
+	case Protobuf:
+		var reading predictv1.Reading
+		err := proto.Unmarshal(data, &reading)
+		if err != nil {
+			return nil, err
+		}
+		return ReadingFields(&reading, s)
+	case MsgPack:
+		var reading map[string]any
+		err := unmarshalMsgPack(data, &reading)
+		if err != nil {
+			return nil, err
+		}
+		if reading == nil {
+			return nil, errors.New("reading must be a map")
+		}
+		return reading, nil
+	default:
+		return nil, fmt.Errorf("unsupported media type %q", mediaType)
+	}
+}
+
+// RequestFields returns the fields of the readings of a PredictRequest message.
+func RequestFields(request *predictv1.PredictRequest, s *schema.Schema) ([]map[string]any, error) {
+	readings := make([]map[string]any, len(request.Readings))
+	for i, reading := range request.Readings {
+		fields, err := ReadingFields(reading, s)
+		if err != nil {
+			return nil, fmt.Errorf("reading %d: %w", i, err)
+		}
+		readings[i] = fields
+	}
+	return readings, nil
+}
+
+// ReadingFields returns the fields of a Reading message. The positional values are named
+// after the sensors of the schema, in order, and must then cover every sensor.
+func ReadingFields(reading *predictv1.Reading, s *schema.Schema) (map[string]any, error) {
+	fields := make(map[string]any, s.Len()+3)
+	fields[schema.MachineIDField] = reading.MachineId
+	if reading.Timestamp != nil {
+		err := reading.Timestamp.CheckValid()
+		if err != nil {
+			return nil, err
+		}
+		fields[schema.TimestampField] = reading.Timestamp.AsTime()
+	}
+	if reading.ReadingId != "" {
+		fields[schema.ReadingIDField] = reading.ReadingId
+	}
+
+	if len(reading.Values) > 0 {
+		if len(reading.Values) != s.Len() {
+			return nil, fmt.Errorf("values must hold the %d sensors of the schema, got %d", s.Len(), len(reading.Values))
+		}
+		for i, name := range s.Names() {
+			fields[name] = reading.Values[i]
+		}
+	}
+	for name, value := range reading.Sensors {
+		if _, ok := fields[name]; ok {
+			return nil, fmt.Errorf("sensor %q is set twice", name)
+		}
+		fields[name] = value
+	}
+
+	return fields, nil
+}
+
+// unmarshalMsgPack decodes a single MessagePack value.
+func unmarshalMsgPack(data []byte, v any) error {
+	r := bytes.NewReader(data)
+	err := msgpack.NewDecoder(r).Decode(v)
+	if err != nil {
+		return err
+	}
+
+	// Trailing bytes are refused, as in JSON
+	if r.Len() > 0 {
+		return errors.New("body must only contain a single MessagePack value")
+	}
+	return nil
+}
+
+// MarshalMsgPack encodes a value in MessagePack, structs being encoded with the same
+// field names as in JSON.
+func MarshalMsgPack(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.SetSortMapKeys(true)
+	err := enc.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ErrorMessage returns the Protobuf body of an error response, message being either
+// a description of the error or the field-level errors of a validation failure.
+func ErrorMessage(message any) *predictv1.Error {
+	switch m := message.(type) {
+	case map[string]string:
+		return &predictv1.Error{Message: "the request contains invalid fields", Fields: m}
+	case string:
+		return &predictv1.Error{Message: m}
+	default:
+		return &predictv1.Error{Message: fmt.Sprint(m)}
+	}
+}
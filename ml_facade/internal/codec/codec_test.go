@@ -0,0 +1,151 @@
+package codec
+
+import (
+	"ml_facade/internal/pb/predictv1"
+	"ml_facade/internal/schema"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestMediaType(t *testing.T) {
+	tests := map[string]string{
+		"":                                JSON,
+		"application/json":                JSON,
+		"text/plain":                      JSON,
+		"application/x-protobuf":          Protobuf,
+		"application/protobuf; proto=foo": Protobuf,
+		"application/msgpack":             MsgPack,
+		"application/x-msgpack":           MsgPack,
+	}
+
+	for contentType, expected := range tests {
+		if mediaType := MediaType(contentType); mediaType != expected {
+			t.Errorf("%q: expected %s, got %s", contentType, expected, mediaType)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]string{
+		"":                    JSON,
+		"*/*":                 JSON,
+		"text/html":           JSON,
+		"application/msgpack": MsgPack,
+		"application/x-protobuf, application/json":    Protobuf,
+		"application/json;q=0.5, application/msgpack": MsgPack,
+		"application/x-protobuf;q=0, */*;q=0.1":       JSON,
+	}
+
+	for accept, expected := range tests {
+		if mediaType := Negotiate(accept); mediaType != expected {
+			t.Errorf("%q: expected %s, got %s", accept, expected, mediaType)
+		}
+	}
+}
+
+func TestDecodeBatch(t *testing.T) {
+	s, err := schema.Parse([]byte(`{"sensors": [{"name": "pressure"}, {"name": "rpm", "type": "integer"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	timestamp := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// Test Case 1: Protobuf readings hold named or positional values
+	t.Run("Protobuf", func(t *testing.T) {
+		// Arrange
+		data, _ := proto.Marshal(&predictv1.PredictRequest{Readings: []*predictv1.Reading{
+			{MachineId: 7, Timestamp: timestamppb.New(timestamp), Sensors: map[string]float64{"pressure": 2.5, "rpm": 1500}},
+			{MachineId: 8, ReadingId: "r-1", Values: []float64{3.5, 1200}},
+		}})
+
+		// Act
+		readings, err := DecodeBatch(Protobuf, data, s)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(readings) != 2 {
+			t.Fatalf("expected 2 readings, got %d", len(readings))
+		}
+		if readings[0]["machine_id"] != int64(7) || readings[0]["timestamp"] != timestamp || readings[0]["pressure"] != 2.5 {
+			t.Errorf("unexpected first reading %v", readings[0])
+		}
+		if readings[1]["reading_id"] != "r-1" || readings[1]["pressure"] != 3.5 || readings[1]["rpm"] != 1200.0 {
+			t.Errorf("unexpected second reading %v", readings[1])
+		}
+		if _, ok := readings[1]["timestamp"]; ok {
+			t.Errorf("expected no timestamp, got %v", readings[1]["timestamp"])
+		}
+	})
+
+	// Test Case 2: MessagePack readings are maps, as in JSON
+	t.Run("MessagePack", func(t *testing.T) {
+		// Arrange
+		data, _ := msgpack.Marshal([]map[string]any{{"machine_id": 7, "pressure": 2.5, "rpm": 1500}})
+
+		// Act
+		readings, err := DecodeBatch(MsgPack, data, s)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(readings) != 1 || readings[0]["pressure"] != 2.5 {
+			t.Errorf("unexpected readings %v", readings)
+		}
+	})
+
+	// Test Case 3: Undecodable bodies are refused
+	t.Run("Malformed Bodies", func(t *testing.T) {
+		positional, _ := proto.Marshal(&predictv1.PredictRequest{Readings: []*predictv1.Reading{{MachineId: 7, Values: []float64{2.5}}}})
+		trailing, _ := msgpack.Marshal([]map[string]any{{"machine_id": 7}})
+		notMaps, _ := msgpack.Marshal([]int{1, 2})
+
+		tests := map[string]struct {
+			mediaType string
+			data      []byte
+		}{
+			"missing positional value": {Protobuf, positional},
+			"invalid protobuf":         {Protobuf, []byte{0xff, 0xff}},
+			"trailing bytes":           {MsgPack, append(trailing, 0x01)},
+			"not maps":                 {MsgPack, notMaps},
+			"json":                     {JSON, []byte(`[]`)},
+		}
+
+		for name, tt := range tests {
+			// Act
+			_, err := DecodeBatch(tt.mediaType, tt.data, s)
+
+			// Assert
+			if err == nil {
+				t.Errorf("%s: expected an error, got none", name)
+			}
+		}
+	})
+}
+
+func TestDecodeReading(t *testing.T) {
+	s, err := schema.Parse([]byte(`{"sensors": [{"name": "pressure"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Arrange
+	data, _ := proto.Marshal(&predictv1.Reading{MachineId: 7, Sensors: map[string]float64{"pressure": 2.5}})
+
+	// Act
+	reading, err := DecodeReading(Protobuf, data, s)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if reading["machine_id"] != int64(7) || reading["pressure"] != 2.5 {
+		t.Errorf("unexpected reading %v", reading)
+	}
+}
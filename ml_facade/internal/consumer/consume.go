@@ -2,16 +2,29 @@ package consumer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	amqp "github.com/rabbitmq/amqp091-go"
-	"time"
+	"ml_facade/internal/logging"
+	"ml_facade/internal/metrics"
+	"ml_facade/internal/service"
+	"sync"
 )
 
+// Consume reads the deliveries of the queue and dispatches them to a fixed set of
+// partitions, keyed by machine ID. Each partition processes its batches one after
+// the other, so the readings of a machine update its anomaly counter in arrival
+// order while different machines are still processed in parallel.
 func (c *RabbitMQConsumer) Consume(ctx context.Context) error {
-	messages, err := c.channel.Consume(
+	c.mu.RLock()
+	channel, connection := c.channel, c.connection
+	c.mu.RUnlock()
+
+	// Messages are acknowledged manually once their records have been persisted
+	messages, err := channel.Consume(
 		c.config.Queue,
 		"",
-		true,
+		false,
 		false,
 		false,
 		false,
@@ -21,97 +34,111 @@ func (c *RabbitMQConsumer) Consume(ctx context.Context) error {
 	}
 
 	closeCh := make(chan *amqp.Error)
-	c.connection.NotifyClose(closeCh)
+	connection.NotifyClose(closeCh)
 
-	semaphore := make(chan struct{}, c.config.NumWorkers)
+	partitions := make([]chan amqp.Delivery, c.config.NumWorkers)
+	var workers sync.WaitGroup
+	for i := range partitions {
+		partitions[i] = make(chan amqp.Delivery, c.config.BatchSize)
+		workers.Add(1)
+		go c.runPartition(ctx, partitions[i], &workers)
+	}
 
-	buffer := make([]amqp.Delivery, 0, c.config.BatchSize)
-	timer := time.NewTimer(c.config.BatchTimeout)
-	defer timer.Stop()
+	// Flush the pending batches of every partition before returning
+	defer func() {
+		for _, partition := range partitions {
+			close(partition)
+		}
+		workers.Wait()
+	}()
 
 	for {
 		select {
 		case msg, ok := <-messages:
 			if !ok {
-				if len(buffer) > 0 {
-					if err := c.dispatchBatch(buffer, semaphore); err != nil {
-						return err
-					}
-				}
 				return nil
 			}
-
-			if msg.Body != nil {
-				buffer = append(buffer, msg)
-				if len(buffer) == 1 {
-					if !timer.Stop() {
-						<-timer.C
-					}
-					timer.Reset(c.config.BatchTimeout)
-				}
-				if len(buffer) >= c.config.BatchSize {
-					if err := c.dispatchBatch(buffer, semaphore); err != nil {
-						return err
-					}
-					buffer = make([]amqp.Delivery, 0, c.config.BatchSize)
-					if !timer.Stop() {
-						<-timer.C
-					}
-					timer.Reset(c.config.BatchTimeout)
-				}
-			}
-
-		case <-timer.C:
-			if len(buffer) > 0 {
-				if err := c.dispatchBatch(buffer, semaphore); err != nil {
-					return err
-				}
-				buffer = make([]amqp.Delivery, 0, c.config.BatchSize)
-			}
-			timer.Reset(c.config.BatchTimeout)
+			partitions[partitionOf(msg, len(partitions), c.service.Schema())] <- msg
 
 		case <-ctx.Done():
 			c.logger.Warn("context canceled, stopping RabbitMQConsumer")
-			if len(buffer) > 0 {
-				if err := c.dispatchBatch(buffer, semaphore); err != nil {
-					return err
-				}
-			}
 			return nil
+
 		case err := <-closeCh:
-			if len(buffer) > 0 {
-				if dispatchErr := c.dispatchBatch(buffer, semaphore); dispatchErr != nil {
-					c.logger.Error(fmt.Sprintf("error dispatching final batch: %v", dispatchErr))
-				}
+			if err != nil {
+				return err
 			}
-			return err
+			return nil
 		}
 	}
 }
 
-// dispatchBatch handles the dispatching of a batch of messages.
-// It acquires a semaphore slot, starts a goroutine for processing,
-// and ensures the semaphore is released after processing.
-func (c *RabbitMQConsumer) dispatchBatch(batch []amqp.Delivery, semaphore chan struct{}) error {
-	// Acquire semaphore slot
-	semaphore <- struct{}{}
-
-	// Make a copy of the batch to avoid data races
-	batchCopy := make([]amqp.Delivery, len(batch))
-	copy(batchCopy, batch)
+// processBatch sends the batch to the ml service and settles every message of the
+// batch: rejected messages are dead-lettered on their own, the others are acknowledged
+// once persisted, or scheduled for a retry otherwise. Duplicates are acknowledged too,
+// unless their original is still being processed and may fail: they are retried until
+// it is stored, or forgotten and processed again.
+//
+// While the ml service is unavailable, the batch waits for it to come back, which
+// pauses the partition. Messages still waiting on shutdown are returned to the queue.
+//
+// The batch is given an ID, added to the log lines it produces and sent to the ml service.
+func (c *RabbitMQConsumer) processBatch(ctx context.Context, msgs []amqp.Delivery) {
+	ctx = logging.WithBatchID(ctx, logging.NewID())
 
-	go c.processBatch(batchCopy, semaphore)
-	return nil
-}
+	metrics.RabbitMQBatchSize.Observe(float64(len(msgs)))
+	metrics.RabbitMQBusyWorkers.Inc()
+	defer metrics.RabbitMQBusyWorkers.Dec()
 
-func (c *RabbitMQConsumer) processBatch(msgs []amqp.Delivery, semaphore chan struct{}) {
-	defer func() {
-		<-semaphore
-	}()
+	if !c.service.Available() {
+		c.logger.WarnContext(ctx, "ml service is unavailable, pausing consumption")
+		if err := c.service.WaitAvailable(ctx); err != nil {
+			for _, msg := range msgs {
+				c.nack(ctx, msg)
+			}
+			return
+		}
+		c.logger.InfoContext(ctx, "ml service is available, resuming consumption")
+	}
 
+	// The batch is processed to completion on shutdown, within the ml service timeout
 	c.wg.Add(1)
-	_, _, err := c.service.HandleMlServiceRequest(msgs, "rabbitmq")
+	result, err := c.service.HandleMlServiceRequest(context.WithoutCancel(ctx), msgs, "rabbitmq")
+
+	rejected := make(map[int]bool, len(result.Rejected))
+	for _, rejection := range result.Rejected {
+		rejected[rejection.Index] = true
+		c.reject(ctx, msgs[rejection.Index], rejection)
+	}
+
 	if err != nil {
-		c.logger.Error(fmt.Sprintf("error handling ml service request: %v", err))
+		c.logger.ErrorContext(ctx, fmt.Sprintf("error handling ml service request: %v", err))
+	}
+
+	inProgress := make(map[int]bool)
+	for _, duplicate := range result.Duplicates {
+		if duplicate.InProgress {
+			inProgress[duplicate.Index] = true
+		}
+	}
+
+	for i, msg := range msgs {
+		if rejected[i] {
+			continue
+		}
+		if inProgress[i] {
+			c.retry(ctx, msg, "the reading is still being processed")
+			continue
+		}
+		// The ml service went down in the meantime: the message does not count as a failed attempt
+		if errors.Is(err, service.ErrMlServiceUnavailable) {
+			c.nack(ctx, msg)
+			continue
+		}
+		if err != nil {
+			c.retry(ctx, msg, err.Error())
+			continue
+		}
+		c.ack(ctx, msg)
 	}
 }
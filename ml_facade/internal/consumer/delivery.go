@@ -0,0 +1,157 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"ml_facade/internal/metrics"
+	"ml_facade/internal/service"
+	"time"
+)
+
+const (
+	retryCountHeader    = "x-retry-count"
+	lastErrorHeader     = "x-last-error"
+	deathReasonHeader   = "x-death-reason"
+	deathDetailHeader   = "x-death-detail"
+	originalQueueHeader = "x-original-queue"
+	failedAtHeader      = "x-failed-at"
+)
+
+const publishTimeout = 5 * time.Second
+
+// reasonMaxRetriesExceeded is the dead letter reason of messages that kept failing.
+const reasonMaxRetriesExceeded = "max_retries_exceeded"
+
+var errPublishNotConfirmed = errors.New("publish was not confirmed by the broker")
+
+// retry schedules a failed message for another attempt through the retry queue.
+// Once the message has been retried MaxRetries times it is dead-lettered instead.
+func (c *RabbitMQConsumer) retry(ctx context.Context, msg amqp.Delivery, reason string) {
+	attempts := retryCount(msg) + 1
+	if attempts > c.config.MaxRetries {
+		c.deadLetter(ctx, msg, reasonMaxRetriesExceeded, reason)
+		return
+	}
+
+	headers := copyHeaders(msg.Headers)
+	headers[retryCountHeader] = int32(attempts)
+	headers[lastErrorHeader] = reason
+
+	err := c.republish("", c.config.RetryQueueName(), msg, headers)
+	if err != nil {
+		c.logger.ErrorContext(ctx, fmt.Sprintf("error scheduling message retry: %v", err))
+		c.nack(ctx, msg)
+		return
+	}
+
+	metrics.RabbitMQRetriedMessages.Inc()
+	c.logger.WarnContext(ctx, fmt.Sprintf("message scheduled for retry %d/%d in %v", attempts, c.config.MaxRetries, c.config.RetryDelay))
+	c.ack(ctx, msg)
+}
+
+// reject dead-letters a message that the ml service refused on its own.
+func (c *RabbitMQConsumer) reject(ctx context.Context, msg amqp.Delivery, rejection service.Rejection) {
+	c.deadLetter(ctx, msg, rejection.Reason, rejection.Detail)
+}
+
+// deadLetter publishes the message to the dead letter exchange, with the reason
+// of the failure in its headers, and acknowledges the original delivery.
+func (c *RabbitMQConsumer) deadLetter(ctx context.Context, msg amqp.Delivery, reason, detail string) {
+	headers := copyHeaders(msg.Headers)
+	headers[retryCountHeader] = int32(retryCount(msg))
+	headers[deathReasonHeader] = reason
+	headers[deathDetailHeader] = detail
+	headers[originalQueueHeader] = c.config.Queue
+	headers[failedAtHeader] = time.Now().UTC().Format(time.RFC3339)
+
+	err := c.republish(c.config.DeadLetterExchangeName(), c.config.Queue, msg, headers)
+	if err != nil {
+		c.logger.ErrorContext(ctx, fmt.Sprintf("error dead-lettering message: %v", err))
+		c.nack(ctx, msg)
+		return
+	}
+
+	metrics.RabbitMQDroppedMessages.WithLabelValues(reason).Inc()
+	c.logger.WarnContext(ctx, fmt.Sprintf("message dead-lettered (%s): %s", reason, detail), "reason", reason)
+	c.ack(ctx, msg)
+}
+
+// republish publishes a copy of the delivery and waits for the broker confirmation.
+func (c *RabbitMQConsumer) republish(exchange, key string, msg amqp.Delivery, headers amqp.Table) error {
+	c.mu.RLock()
+	channel := c.channel
+	c.mu.RUnlock()
+
+	if channel == nil {
+		return amqp.ErrClosed
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	confirmation, err := channel.PublishWithDeferredConfirmWithContext(
+		ctx,
+		exchange,
+		key,
+		false,
+		false,
+		amqp.Publishing{
+			Headers:         headers,
+			ContentType:     msg.ContentType,
+			ContentEncoding: msg.ContentEncoding,
+			DeliveryMode:    amqp.Persistent,
+			MessageId:       msg.MessageId,
+			Timestamp:       msg.Timestamp,
+			Body:            msg.Body,
+		})
+	if err != nil {
+		return err
+	}
+
+	confirmed, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return errPublishNotConfirmed
+	}
+	return nil
+}
+
+func (c *RabbitMQConsumer) ack(ctx context.Context, msg amqp.Delivery) {
+	if err := msg.Ack(false); err != nil {
+		c.logger.ErrorContext(ctx, fmt.Sprintf("error acknowledging message: %v", err))
+	}
+}
+
+// nack returns the message to the work queue. It is used when the message could
+// not be moved to the retry or dead letter queue, or could not be processed yet.
+func (c *RabbitMQConsumer) nack(ctx context.Context, msg amqp.Delivery) {
+	if err := msg.Nack(false, true); err != nil {
+		c.logger.ErrorContext(ctx, fmt.Sprintf("error rejecting message: %v", err))
+	}
+}
+
+// retryCount returns the number of times the message already went through the retry queue.
+func retryCount(msg amqp.Delivery) int {
+	switch v := msg.Headers[retryCountHeader].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
+func copyHeaders(headers amqp.Table) amqp.Table {
+	copied := make(amqp.Table, len(headers)+4)
+	for k, v := range headers {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,93 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"ml_facade/internal/codec"
+	"ml_facade/internal/schema"
+	"ml_facade/internal/validator"
+	"sync"
+	"time"
+)
+
+// runPartition accumulates the deliveries of a partition into batches and processes
+// them sequentially. A batch is processed when it reaches the configured batch size
+// or when the batch timeout expires, whichever comes first.
+//
+// Note that a message scheduled for a retry goes back to the end of the queue and
+// is therefore processed after the readings that arrived in the meantime.
+func (c *RabbitMQConsumer) runPartition(ctx context.Context, deliveries <-chan amqp.Delivery, workers *sync.WaitGroup) {
+	defer workers.Done()
+
+	buffer := make([]amqp.Delivery, 0, c.config.BatchSize)
+	timer := time.NewTimer(c.config.BatchTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case msg, ok := <-deliveries:
+			if !ok {
+				if len(buffer) > 0 {
+					c.processBatch(ctx, buffer)
+				}
+				return
+			}
+
+			buffer = append(buffer, msg)
+			if len(buffer) == 1 {
+				resetTimer(timer, c.config.BatchTimeout)
+			}
+			if len(buffer) >= c.config.BatchSize {
+				c.processBatch(ctx, buffer)
+				buffer = make([]amqp.Delivery, 0, c.config.BatchSize)
+			}
+
+		case <-timer.C:
+			if len(buffer) > 0 {
+				c.processBatch(ctx, buffer)
+				buffer = make([]amqp.Delivery, 0, c.config.BatchSize)
+			}
+			timer.Reset(c.config.BatchTimeout)
+		}
+	}
+}
+
+// partitionOf returns the partition a delivery belongs to, based on its machine ID,
+// the delivery being decoded according to its content type like parseDelivery does.
+// Deliveries whose machine ID cannot be read all go to the first partition, where
+// they will be rejected.
+func partitionOf(msg amqp.Delivery, partitions int, s *schema.Schema) int {
+	var machineID int
+
+	mediaType := codec.MediaType(msg.ContentType)
+	if mediaType == codec.JSON {
+		var key struct {
+			MachineID int `json:"machine_id"`
+		}
+		if err := json.Unmarshal(msg.Body, &key); err != nil {
+			return 0
+		}
+		machineID = key.MachineID
+	} else {
+		fields, err := codec.DecodeReading(mediaType, msg.Body, s)
+		if err != nil {
+			return 0
+		}
+		// The other fields of the reading are validated once the batch is processed
+		machineID = s.DecodeMap(validator.New(), fields).MachineID
+	}
+
+	return int(uint(machineID) % uint(partitions))
+}
+
+// resetTimer stops the timer, drains its channel if needed and resets it.
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
@@ -0,0 +1,127 @@
+package consumer
+
+import (
+	"ml_facade/internal/pb/predictv1"
+	"ml_facade/internal/schema"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestPartitionOf(t *testing.T) {
+	sensorSchema := schema.Default()
+
+	// Test Case 1: Deliveries of the same machine go to the same partition
+	t.Run("Same Machine", func(t *testing.T) {
+		// Arrange
+		first := amqp.Delivery{Body: []byte(`{"machine_id": 7, "sensor_00": 0.1}`)}
+		second := amqp.Delivery{Body: []byte(`{"sensor_00": 0.2, "machine_id": 7}`)}
+
+		// Act
+		p1 := partitionOf(first, 100, sensorSchema)
+		p2 := partitionOf(second, 100, sensorSchema)
+
+		// Assert
+		if p1 != p2 {
+			t.Errorf("expected the same partition, got %d and %d", p1, p2)
+		}
+	})
+
+	// Test Case 2: Deliveries are spread across partitions
+	t.Run("Different Machines", func(t *testing.T) {
+		// Arrange
+		first := amqp.Delivery{Body: []byte(`{"machine_id": 7}`)}
+		second := amqp.Delivery{Body: []byte(`{"machine_id": 8}`)}
+
+		// Act
+		p1 := partitionOf(first, 100, sensorSchema)
+		p2 := partitionOf(second, 100, sensorSchema)
+
+		// Assert
+		if p1 == p2 {
+			t.Errorf("expected different partitions, got %d for both", p1)
+		}
+	})
+
+	// Test Case 3: Malformed deliveries go to the first partition
+	t.Run("Malformed Delivery", func(t *testing.T) {
+		// Arrange
+		msg := amqp.Delivery{Body: []byte(`{invalid json}`)}
+
+		// Act
+		p := partitionOf(msg, 100, sensorSchema)
+
+		// Assert
+		if p != 0 {
+			t.Errorf("expected partition 0, got %d", p)
+		}
+	})
+
+	// Test Case 4: Negative machine IDs stay within bounds
+	t.Run("Negative Machine ID", func(t *testing.T) {
+		// Arrange
+		msg := amqp.Delivery{Body: []byte(`{"machine_id": -3}`)}
+
+		// Act
+		p := partitionOf(msg, 7, sensorSchema)
+
+		// Assert
+		if p < 0 || p >= 7 {
+			t.Errorf("expected partition within [0, 7), got %d", p)
+		}
+	})
+
+	// Test Case 5: Protobuf deliveries are partitioned by their machine ID
+	t.Run("Protobuf Delivery", func(t *testing.T) {
+		// Arrange
+		body, err := proto.Marshal(&predictv1.Reading{MachineId: 7, Sensors: map[string]float64{"sensor_00": 0.1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := amqp.Delivery{ContentType: "application/x-protobuf", Body: body}
+		other := amqp.Delivery{Body: []byte(`{"machine_id": 7}`)}
+
+		// Act
+		p := partitionOf(msg, 100, sensorSchema)
+
+		// Assert
+		if expected := partitionOf(other, 100, sensorSchema); p != expected || p == 0 {
+			t.Errorf("expected the partition %d of machine 7, got %d", expected, p)
+		}
+	})
+
+	// Test Case 6: MessagePack deliveries are partitioned by their machine ID
+	t.Run("MessagePack Delivery", func(t *testing.T) {
+		// Arrange
+		body, err := msgpack.Marshal(map[string]any{"machine_id": 7, "sensor_00": 0.1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := amqp.Delivery{ContentType: "application/msgpack", Body: body}
+		other := amqp.Delivery{Body: []byte(`{"machine_id": 7}`)}
+
+		// Act
+		p := partitionOf(msg, 100, sensorSchema)
+
+		// Assert
+		if expected := partitionOf(other, 100, sensorSchema); p != expected || p == 0 {
+			t.Errorf("expected the partition %d of machine 7, got %d", expected, p)
+		}
+	})
+
+	// Test Case 7: Malformed binary deliveries go to the first partition
+	t.Run("Malformed Binary Delivery", func(t *testing.T) {
+		// Arrange
+		msg := amqp.Delivery{ContentType: "application/msgpack", Body: []byte{0xc1}}
+
+		// Act
+		p := partitionOf(msg, 100, sensorSchema)
+
+		// Assert
+		if p != 0 {
+			t.Errorf("expected partition 0, got %d", p)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package consumer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"log/slog"
@@ -11,6 +12,8 @@ import (
 	"time"
 )
 
+var errNotConnected = errors.New("not connected to rabbitmq")
+
 type RabbitMQConsumer struct {
 	logger     *slog.Logger
 	config     config.CfgRabbitMQConsumer
@@ -19,6 +22,7 @@ type RabbitMQConsumer struct {
 	connected  chan bool
 	service    *service.MlService
 	wg         *sync.WaitGroup
+	mu         sync.RWMutex
 }
 
 func NewRabbitMQConsumer(cfg config.CfgRabbitMQConsumer, logger *slog.Logger, mlService *service.MlService, wg *sync.WaitGroup) *RabbitMQConsumer {
@@ -36,13 +40,44 @@ func (c *RabbitMQConsumer) connect() error {
 	if err != nil {
 		return err
 	}
-	c.connection = conn
 
 	ch, err := conn.Channel()
 	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	if err := c.declareTopology(ch); err != nil {
+		conn.Close()
 		return err
 	}
+
+	c.mu.Lock()
+	c.connection = conn
 	c.channel = ch
+	c.mu.Unlock()
+
+	c.connected <- true
+	return nil
+}
+
+// declareTopology declares the work queue along with the retry queue and the
+// dead letter exchange/queue used when processing a message fails.
+//
+// Messages published to the retry queue expire after the configured retry delay
+// and are routed back to the work queue by the default exchange.
+func (c *RabbitMQConsumer) declareTopology(ch *amqp.Channel) error {
+	err := ch.Qos(c.config.PrefetchCount(), 0, false)
+	if err != nil {
+		return err
+	}
+
+	// Publisher confirms make sure a message reached the retry or dead letter
+	// queue before the original delivery is acknowledged.
+	err = ch.Confirm(false)
+	if err != nil {
+		return err
+	}
 
 	_, err = ch.QueueDeclare(
 		c.config.Queue,
@@ -55,8 +90,50 @@ func (c *RabbitMQConsumer) connect() error {
 		return err
 	}
 
-	c.connected <- true
-	return nil
+	_, err = ch.QueueDeclare(
+		c.config.RetryQueueName(),
+		true,
+		false,
+		false,
+		false,
+		amqp.Table{
+			"x-message-ttl":             c.config.RetryDelay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": c.config.Queue,
+		})
+	if err != nil {
+		return err
+	}
+
+	err = ch.ExchangeDeclare(
+		c.config.DeadLetterExchangeName(),
+		amqp.ExchangeFanout,
+		true,
+		false,
+		false,
+		false,
+		nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = ch.QueueDeclare(
+		c.config.DeadLetterQueueName(),
+		true,
+		false,
+		false,
+		false,
+		nil)
+	if err != nil {
+		return err
+	}
+
+	return ch.QueueBind(
+		c.config.DeadLetterQueueName(),
+		"",
+		c.config.DeadLetterExchangeName(),
+		false,
+		nil)
 }
 
 func (c *RabbitMQConsumer) retryConnect(ctx context.Context) {
@@ -86,7 +163,24 @@ func (c *RabbitMQConsumer) retryConnect(ctx context.Context) {
 	}
 }
 
+// Ping reports whether the consumer is currently connected to RabbitMQ.
+func (c *RabbitMQConsumer) Ping(_ context.Context) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.connection == nil || c.connection.IsClosed() {
+		return errNotConnected
+	}
+	if c.channel == nil || c.channel.IsClosed() {
+		return errNotConnected
+	}
+	return nil
+}
+
 func (c *RabbitMQConsumer) Close() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	if c.channel != nil {
 		c.channel.Close()
 	}
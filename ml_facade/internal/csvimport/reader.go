@@ -0,0 +1,223 @@
+package csvimport
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"ml_facade/internal/schema"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampLayouts are the accepted formats of the timestamp column. Timestamps without
+// a time zone, as found in the historical exports, are read as UTC.
+var timestampLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05"}
+
+// Options tells how the columns of a CSV file map to the fields of a reading.
+//
+// Mapping maps column names to a sensor or to one of the machine_id, timestamp and
+// reading_id fields, an empty field meaning the column is ignored. Columns that are not
+// in Mapping are mapped to the field of the same name, if any, and ignored otherwise.
+// MachineID is used for every row when no column holds the machine ID. Empty cells of
+// sensor columns take the FillEmpty value when it is set, and are refused otherwise.
+type Options struct {
+	Mapping   map[string]string
+	MachineID *int
+	FillEmpty *float64
+}
+
+// MappingError is returned when the header of the file cannot be mapped to a reading.
+type MappingError struct {
+	Errors map[string]string
+}
+
+func (e *MappingError) Error() string {
+	return fmt.Sprintf("invalid column mapping: %v", e.Errors)
+}
+
+// RowError is returned for a row that cannot be turned into a reading. Line is the
+// line of the row in the file, the header being on line 1.
+type RowError struct {
+	Line   int
+	Detail string
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Detail)
+}
+
+// Row is a row of the file turned into a JSON reading, as accepted by the predict endpoint.
+type Row struct {
+	Line    int
+	Reading json.RawMessage
+}
+
+// column is a column of the file mapped to a field of the reading.
+type column struct {
+	index int
+	field string
+}
+
+// Reader reads the readings of a CSV file one row at a time.
+type Reader struct {
+	csv       *csv.Reader
+	columns   []column
+	machineID *int
+	fillEmpty *float64
+}
+
+// NewReader reads the header of the file and maps its columns to the fields of the
+// schema. A MappingError is returned if a sensor or the machine ID is not mapped, or
+// if the mapping refers to an unknown column or field.
+func NewReader(r io.Reader, s *schema.Schema, opts Options) (*Reader, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, &MappingError{Errors: map[string]string{"header": "must be provided"}}
+		}
+		return nil, err
+	}
+	header = append([]string(nil), header...)
+
+	columns, mappingErrors := mapColumns(header, s, opts)
+	if len(mappingErrors) > 0 {
+		return nil, &MappingError{Errors: mappingErrors}
+	}
+
+	return &Reader{csv: reader, columns: columns, machineID: opts.MachineID, fillEmpty: opts.FillEmpty}, nil
+}
+
+// mapColumns resolves the field of every column of the header.
+func mapColumns(header []string, s *schema.Schema, opts Options) ([]column, map[string]string) {
+	mappingErrors := make(map[string]string)
+	known := func(field string) bool {
+		return s.Has(field) || field == schema.MachineIDField || field == schema.TimestampField || field == schema.ReadingIDField
+	}
+
+	for name, field := range opts.Mapping {
+		if !slices.Contains(header, name) {
+			mappingErrors[name] = "is not a column of the file"
+		} else if field != "" && !known(field) {
+			mappingErrors[name] = fmt.Sprintf("is mapped to unknown field %q", field)
+		}
+	}
+
+	var columns []column
+	mapped := make(map[string]string)
+	for i, name := range header {
+		field, ok := opts.Mapping[name]
+		if !ok {
+			field = name
+		}
+		if field == "" || !known(field) {
+			continue
+		}
+
+		if previous, exists := mapped[field]; exists {
+			mappingErrors[name] = fmt.Sprintf("is mapped to %q, already mapped from column %q", field, previous)
+			continue
+		}
+		mapped[field] = name
+		columns = append(columns, column{index: i, field: field})
+	}
+
+	for _, sensor := range s.Names() {
+		if _, ok := mapped[sensor]; !ok {
+			mappingErrors[sensor] = "is not mapped to any column"
+		}
+	}
+	if _, ok := mapped[schema.MachineIDField]; !ok && opts.MachineID == nil {
+		mappingErrors[schema.MachineIDField] = "must be mapped to a column or provided as a parameter"
+	}
+
+	return columns, mappingErrors
+}
+
+// Next returns the next row of the file, or io.EOF once the file is exhausted. A
+// RowError is returned for a row that cannot be turned into a reading, in which case
+// the following rows can still be read.
+func (r *Reader) Next() (Row, error) {
+	record, err := r.csv.Read()
+	if err != nil {
+		var parseError *csv.ParseError
+		if errors.As(err, &parseError) {
+			return Row{}, &RowError{Line: parseError.Line, Detail: parseError.Err.Error()}
+		}
+		return Row{}, err
+	}
+	line, _ := r.csv.FieldPos(0)
+
+	reading := make(map[string]any, len(r.columns)+1)
+	if r.machineID != nil {
+		reading[schema.MachineIDField] = *r.machineID
+	}
+
+	var problems []string
+	for _, c := range r.columns {
+		cell := strings.TrimSpace(record[c.index])
+		value, problem := r.parseCell(c.field, cell)
+		if problem != "" {
+			problems = append(problems, fmt.Sprintf("%s %s", c.field, problem))
+			continue
+		}
+		reading[c.field] = value
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return Row{}, &RowError{Line: line, Detail: strings.Join(problems, "; ")}
+	}
+
+	data, err := json.Marshal(reading)
+	if err != nil {
+		return Row{}, &RowError{Line: line, Detail: err.Error()}
+	}
+	return Row{Line: line, Reading: data}, nil
+}
+
+// parseCell converts a cell to the JSON value of its field, returning a message if it is invalid.
+func (r *Reader) parseCell(field, cell string) (any, string) {
+	switch field {
+	case schema.MachineIDField:
+		id, err := strconv.Atoi(cell)
+		if err != nil {
+			return nil, "must be an integer"
+		}
+		return id, ""
+	case schema.TimestampField:
+		if cell == "" {
+			return nil, ""
+		}
+		for _, layout := range timestampLayouts {
+			if t, err := time.Parse(layout, cell); err == nil {
+				return t.Format(time.RFC3339Nano), ""
+			}
+		}
+		return nil, "must be an RFC 3339 or \"2006-01-02 15:04:05\" timestamp"
+	case schema.ReadingIDField:
+		if cell == "" {
+			return nil, ""
+		}
+		return cell, ""
+	}
+
+	if cell == "" {
+		if r.fillEmpty == nil {
+			return nil, "must be provided"
+		}
+		return *r.fillEmpty, ""
+	}
+	value, err := strconv.ParseFloat(cell, 64)
+	if err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+		return nil, "must be a finite number"
+	}
+	return value, ""
+}
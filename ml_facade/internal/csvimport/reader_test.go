@@ -0,0 +1,126 @@
+package csvimport
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"ml_facade/internal/schema"
+)
+
+func TestNewReader(t *testing.T) {
+	// Arrange
+	s, err := schema.Parse([]byte(`{"sensors": [{"name": "pressure"}, {"name": "rpm", "type": "integer"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	machineID := 7
+
+	tests := map[string]struct {
+		header string
+		opts   Options
+		field  string
+	}{
+		"missing sensor":     {"machine_id,pressure", Options{}, "rpm"},
+		"missing machine":    {"pressure,rpm", Options{}, "machine_id"},
+		"unknown column":     {"pressure,rpm", Options{MachineID: &machineID, Mapping: map[string]string{"speed": "rpm"}}, "speed"},
+		"unknown field":      {"pressure,rpm,speed", Options{MachineID: &machineID, Mapping: map[string]string{"speed": "velocity"}}, "speed"},
+		"mapped twice":       {"pressure,rpm,speed", Options{MachineID: &machineID, Mapping: map[string]string{"speed": "rpm"}}, "speed"},
+		"ignored sensor":     {"pressure,rpm", Options{MachineID: &machineID, Mapping: map[string]string{"rpm": ""}}, "rpm"},
+		"empty file":         {"", Options{MachineID: &machineID}, "header"},
+		"valid with mapping": {"p,speed", Options{MachineID: &machineID, Mapping: map[string]string{"p": "pressure", "speed": "rpm"}}, ""},
+	}
+
+	for name, tt := range tests {
+		// Act
+		_, err := NewReader(strings.NewReader(tt.header), s, tt.opts)
+
+		// Assert
+		if tt.field == "" {
+			if err != nil {
+				t.Errorf("%s: expected no error, got %v", name, err)
+			}
+			continue
+		}
+		var mappingError *MappingError
+		if !errors.As(err, &mappingError) {
+			t.Errorf("%s: expected a mapping error, got %v", name, err)
+			continue
+		}
+		if mappingError.Errors[tt.field] == "" {
+			t.Errorf("%s: expected an error on %s, got %v", name, tt.field, mappingError.Errors)
+		}
+	}
+}
+
+func TestReaderNext(t *testing.T) {
+	// Arrange
+	s, err := schema.Parse([]byte(`{"sensors": [{"name": "pressure"}, {"name": "rpm", "type": "integer"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fill := 0.0
+	file := strings.Join([]string{
+		",timestamp,pressure,rpm,machine_status",
+		"0,2018-04-01 00:00:00,2.5,1500,NORMAL",
+		"1,2018-04-01 00:01:00,,1500,NORMAL",
+		"2,yesterday,abc,1500,NORMAL",
+		"3,2018-04-01 00:03:00,2.5",
+	}, "\n")
+	machineID := 7
+	reader, err := NewReader(strings.NewReader(file), s, Options{MachineID: &machineID, FillEmpty: &fill})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	var rows []Row
+	var rowErrors []*RowError
+	for {
+		row, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var rowError *RowError
+		if errors.As(err, &rowError) {
+			rowErrors = append(rowErrors, rowError)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row)
+	}
+
+	// Assert
+	if len(rows) != 2 || len(rowErrors) != 2 {
+		t.Fatalf("expected 2 rows and 2 errors, got %d and %d", len(rows), len(rowErrors))
+	}
+
+	var reading map[string]any
+	if err := json.Unmarshal(rows[0].Reading, &reading); err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].Line != 2 || reading["machine_id"] != 7.0 || reading["timestamp"] != "2018-04-01T00:00:00Z" || reading["pressure"] != 2.5 {
+		t.Errorf("unexpected reading on line %d: %v", rows[0].Line, reading)
+	}
+	if _, ok := reading["machine_status"]; ok {
+		t.Errorf("expected the columns that are not fields to be ignored, got %v", reading)
+	}
+
+	if err := json.Unmarshal(rows[1].Reading, &reading); err != nil {
+		t.Fatal(err)
+	}
+	if reading["pressure"] != 0.0 {
+		t.Errorf("expected the empty cell to be filled, got %v", reading["pressure"])
+	}
+
+	if rowErrors[0].Line != 4 || !strings.Contains(rowErrors[0].Detail, "pressure") || !strings.Contains(rowErrors[0].Detail, "timestamp") {
+		t.Errorf("expected the invalid cells of line 4 to be reported, got %+v", rowErrors[0])
+	}
+	if rowErrors[1].Line != 5 {
+		t.Errorf("expected the short row on line 5 to be reported, got %+v", rowErrors[1])
+	}
+}
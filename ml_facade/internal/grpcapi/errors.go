@@ -0,0 +1,71 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"ml_facade/internal/service"
+	"sort"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// predictError converts an error of the predict pipeline to a gRPC status, in the way
+// the HTTP API converts it to a status code. Validation errors list the invalid fields
+// in a BadRequest detail, and rate limit errors carry when to retry in a RetryInfo detail.
+func (s *Server) predictError(method string, err error) error {
+	var validationError *service.ValidationError
+	var rateLimitError *service.RateLimitError
+
+	switch {
+	case errors.As(err, &validationError):
+		return invalidArgument(validationError.Errors)
+	case errors.Is(err, service.ErrMalformedBody):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.As(err, &rateLimitError):
+		return resourceExhausted(rateLimitError.Error(), rateLimitError.RetryAfter)
+	case errors.Is(err, service.ErrMlServiceUnavailable):
+		return status.Error(codes.Unavailable, "the ml service is temporarily unavailable, please retry later")
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		return s.serverError(method, err)
+	}
+}
+
+// invalidArgument returns an InvalidArgument status describing the invalid fields.
+func invalidArgument(errs map[string]string) error {
+	fields := make([]string, 0, len(errs))
+	for field := range errs {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	badRequest := &errdetails.BadRequest{}
+	for _, field := range fields {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: errs[field],
+		})
+	}
+
+	st, err := status.New(codes.InvalidArgument, "the request contains invalid fields").WithDetails(badRequest)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "the request contains invalid fields")
+	}
+	return st.Err()
+}
+
+// resourceExhausted returns a ResourceExhausted status telling when to retry.
+func resourceExhausted(message string, retryAfter time.Duration) error {
+	retryInfo := &errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}
+
+	st, err := status.New(codes.ResourceExhausted, message).WithDetails(retryInfo)
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, message)
+	}
+	return st.Err()
+}
@@ -0,0 +1,236 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ml_facade/internal/auth"
+	"ml_facade/internal/metrics"
+	"ml_facade/internal/models/postgres_models"
+	"ml_facade/internal/pb/predictv1"
+	"ml_facade/internal/ratelimit"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// recoverUnary turns the panics of a unary call into an Internal error.
+func (s *Server) recoverUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = s.serverError(info.FullMethod, fmt.Errorf("%s", p))
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+// recoverStream turns the panics of a streaming call into an Internal error.
+func (s *Server) recoverStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = s.serverError(info.FullMethod, fmt.Errorf("%s", p))
+		}
+	}()
+
+	return handler(srv, stream)
+}
+
+// authorizeUnary refuses the unary calls to the predict service whose API key was not
+// granted the predict:write scope.
+func (s *Server) authorizeUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// authorizeStream refuses the streaming calls to the predict service whose API key was
+// not granted the predict:write scope.
+func (s *Server) authorizeStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authorize(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+}
+
+// authorize authenticates a call with the API key of its authorization or x-api-key
+// metadata, like the HTTP API, and returns a copy of the context holding the key. The
+// health and reflection services are left open.
+func (s *Server) authorize(ctx context.Context, method string) (context.Context, error) {
+	if !s.authenticator.Enabled() || !predictMethod(method) {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	plaintext, err := auth.KeyFromHeaders(firstValue(md, "authorization"), firstValue(md, "x-api-key"))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired api key")
+	}
+	if plaintext == "" {
+		return nil, status.Error(codes.Unauthenticated, "you must send an api key to access this service")
+	}
+
+	key, err := s.authenticator.Authenticate(ctx, plaintext)
+	switch {
+	case errors.Is(err, auth.ErrInvalidKey):
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired api key")
+	case err != nil:
+		return nil, s.serverError(method, err)
+	case !key.Scopes.Include(postgres_models.ScopePredictWrite):
+		return nil, status.Errorf(codes.PermissionDenied, "your api key must have the %s scope to access this service", postgres_models.ScopePredictWrite)
+	}
+
+	s.authenticator.LogUse(ctx, key, "grpc", method)
+	return context.WithValue(ctx, apiKeyContextKey, &key), nil
+}
+
+// rateLimitPeerUnary limits the unary calls of every peer address with the IP rate
+// limiter, whatever their API key. It runs before authorizeUnary, so that the calls with
+// an invalid API key are limited too.
+func (s *Server) rateLimitPeerUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	err := s.limitCall(ctx, info.FullMethod, s.ipLimiter, "ip", peerIP(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// rateLimitPeerStream limits the streaming calls of every peer address. A stream spends
+// a single token however many messages it carries, its readings being limited by the
+// machine rate limiter.
+func (s *Server) rateLimitPeerStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := s.limitCall(stream.Context(), info.FullMethod, s.ipLimiter, "ip", peerIP(stream.Context()))
+	if err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// rateLimitClientUnary limits the unary calls of every client once authorizeUnary
+// identified it: by its API key, and by its peer address for the anonymous calls.
+func (s *Server) rateLimitClientUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	err := s.limitCall(ctx, info.FullMethod, s.limiter, "client", clientKey(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// rateLimitClientStream limits the streaming calls of every client once authorizeStream
+// identified it, like rateLimitClientUnary.
+func (s *Server) rateLimitClientStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := s.limitCall(stream.Context(), info.FullMethod, s.limiter, "client", clientKey(stream.Context()))
+	if err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// limitCall spends a token of the client's bucket in the limiter, sharing the buckets of
+// the HTTP API. It returns a ResourceExhausted status telling when to retry once the
+// client is limited. The health and reflection services are left open.
+func (s *Server) limitCall(ctx context.Context, method string, limiter *ratelimit.Limiter, name, client string) error {
+	if limiter == nil || !predictMethod(method) {
+		return nil
+	}
+
+	decision := limiter.Allow(ctx, client)
+	if !decision.Allowed {
+		metrics.RateLimited.WithLabelValues(name).Inc()
+		return resourceExhausted("rate limit exceeded", decision.RetryAfter)
+	}
+	return nil
+}
+
+// predictMethod reports whether the method belongs to the predict service.
+func predictMethod(method string) bool {
+	return strings.HasPrefix(method, "/"+predictv1.PredictService_ServiceDesc.ServiceName+"/")
+}
+
+// clientKey identifies the client of a call by its API key, or by its peer address for
+// the anonymous calls, like the clients of the HTTP API.
+func clientKey(ctx context.Context) string {
+	if key := contextGetApiKey(ctx); key != nil {
+		return fmt.Sprintf("key:%d", key.ID)
+	}
+	return "ip:" + peerIP(ctx)
+}
+
+// peerIP returns the IP address the call was sent from.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	ip, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return ip
+}
+
+type contextKey string
+
+const apiKeyContextKey = contextKey("apiKey")
+
+// contextGetApiKey returns the key the call was authenticated with, or nil if it was
+// not authenticated.
+func contextGetApiKey(ctx context.Context) *postgres_models.ApiKey {
+	key, _ := ctx.Value(apiKeyContextKey).(*postgres_models.ApiKey)
+	return key
+}
+
+// serverStream overrides the context of a stream, to hand the values added by an
+// interceptor to the next ones.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func firstValue(md metadata.MD, key string) string {
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// instrumentUnary records the number and the latency of the unary calls.
+func (s *Server) instrumentUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	observe(info.FullMethod, start, err)
+	return resp, err
+}
+
+// instrumentStream records the number and the duration of the streaming calls.
+func (s *Server) instrumentStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	observe(info.FullMethod, start, err)
+	return err
+}
+
+func observe(method string, start time.Time, err error) {
+	metrics.GrpcRequests.WithLabelValues(method, status.Code(err).String()).Inc()
+	metrics.GrpcRequestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// serverError logs an unexpected error and hides it from the client.
+func (s *Server) serverError(method string, err error) error {
+	s.logger.Error(err.Error(), "method", method)
+	return status.Error(codes.Internal, "the server encountered a problem and could not process your request")
+}
@@ -0,0 +1,150 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"ml_facade/internal/pb/predictv1"
+	"sort"
+	"time"
+)
+
+// reasonProcessingFailed is the reason of the readings whose micro-batch could not be scored.
+const reasonProcessingFailed = "processing_failed"
+
+// Predict scores a batch of readings, like the predict route of the HTTP API.
+func (s *Server) Predict(ctx context.Context, req *predictv1.PredictRequest) (*predictv1.PredictResponse, error) {
+	s.wg.Add(1)
+	result, err := s.service.HandleMlServiceRequest(ctx, req, origin)
+	if err != nil {
+		return nil, s.predictError(predictv1.PredictService_Predict_FullMethodName, err)
+	}
+
+	return result.PredictResponse(), nil
+}
+
+// StreamReadings scores the readings of a stream in micro-batches, a batch being sent
+// once it holds StreamBatchSize readings or StreamBatchWait after its first reading.
+//
+// Invalid readings are rejected on their own and reported in the response, along with
+// the readings of the micro-batches that could not be scored. When the server shuts
+// down, the readings received so far are scored and the response is sent right away:
+// the readings after the first Readings ones were not received.
+func (s *Server) StreamReadings(stream predictv1.PredictService_StreamReadingsServer) error {
+	ctx := stream.Context()
+
+	// The stream is read in the background so that the micro-batches are sent in time
+	readings := make(chan *predictv1.Reading)
+	recvErr := make(chan error, 1)
+	go func() {
+		defer close(readings)
+		for {
+			reading, err := stream.Recv()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					recvErr <- err
+				}
+				return
+			}
+
+			select {
+			case readings <- reading:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	response := &predictv1.StreamReadingsResponse{Duplicates: []int64{}, Rejected: []*predictv1.Rejection{}}
+	pending := make([]*predictv1.Reading, 0, s.config.GrpcServer.StreamBatchSize)
+	var wait <-chan time.Time
+
+	sendAndClose := func() error {
+		sort.SliceStable(response.Rejected, func(i, j int) bool {
+			return response.Rejected[i].Index < response.Rejected[j].Index
+		})
+		return stream.SendAndClose(response)
+	}
+	flush := func() {
+		if len(pending) > 0 {
+			s.scoreBatch(ctx, pending, int(response.Readings)-len(pending), response)
+			pending = pending[:0]
+		}
+		wait = nil
+	}
+
+	for {
+		select {
+		case reading, ok := <-readings:
+			if !ok {
+				select {
+				case err := <-recvErr:
+					return err
+				default:
+				}
+
+				flush()
+				return sendAndClose()
+			}
+
+			response.Readings++
+			if len(pending) == 0 {
+				wait = time.After(s.config.GrpcServer.StreamBatchWait)
+			}
+			pending = append(pending, reading)
+			if len(pending) >= s.config.GrpcServer.StreamBatchSize {
+				flush()
+			}
+		case <-wait:
+			flush()
+		case <-s.shutdown:
+			flush()
+			return sendAndClose()
+		case <-ctx.Done():
+			return s.predictError(predictv1.PredictService_StreamReadings_FullMethodName, ctx.Err())
+		}
+	}
+}
+
+// scoreBatch scores a micro-batch of readings and adds its outcome to the response,
+// offset being the position of the batch in the stream. The readings of a batch that
+// could not be scored are rejected as a whole.
+func (s *Server) scoreBatch(ctx context.Context, readings []*predictv1.Reading, offset int, response *predictv1.StreamReadingsResponse) {
+	s.wg.Add(1)
+	result, err := s.service.HandleMlServiceRequest(ctx, readings, origin)
+
+	rejected := make(map[int]bool, len(result.Rejected))
+	for _, rejection := range result.Rejected {
+		rejected[rejection.Index] = true
+		response.Rejected = append(response.Rejected, &predictv1.Rejection{
+			Index:  int64(offset + rejection.Index),
+			Reason: rejection.Reason,
+			Detail: rejection.Detail,
+		})
+	}
+
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("error scoring readings %d to %d of a stream: %v", offset, offset+len(readings)-1, err))
+		for i := range readings {
+			if !rejected[i] {
+				response.Rejected = append(response.Rejected, &predictv1.Rejection{
+					Index:  int64(offset + i),
+					Reason: reasonProcessingFailed,
+					Detail: err.Error(),
+				})
+			}
+		}
+		return
+	}
+
+	for _, duplicate := range result.Duplicates {
+		response.Duplicates = append(response.Duplicates, int64(offset+duplicate.Index))
+	}
+	for _, prediction := range result.Predictions {
+		response.Accepted++
+		if prediction.Anomaly {
+			response.Anomalies++
+		}
+	}
+}
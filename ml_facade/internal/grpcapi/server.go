@@ -0,0 +1,112 @@
+// Package grpcapi implements the gRPC ingestion server, which scores the readings of
+// the edge gateways through the same pipeline as the HTTP API.
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"ml_facade/config"
+	"ml_facade/internal/auth"
+	"ml_facade/internal/pb/predictv1"
+	"ml_facade/internal/ratelimit"
+	"ml_facade/internal/service"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// origin is the origin of the records stored by the gRPC server.
+const origin = "grpc"
+
+// shutdownTimeout bounds how long the calls in flight are waited for on shutdown.
+const shutdownTimeout = 5 * time.Second
+
+type Server struct {
+	predictv1.UnimplementedPredictServiceServer
+
+	config        config.Config
+	logger        *slog.Logger
+	service       *service.MlService
+	authenticator *auth.Authenticator
+	limiter       *ratelimit.Limiter
+	ipLimiter     *ratelimit.Limiter
+	wg            *sync.WaitGroup
+	shutdown      chan struct{}
+}
+
+func NewServer(
+	config config.Config,
+	logger *slog.Logger,
+	service *service.MlService,
+	authenticator *auth.Authenticator,
+	limiter *ratelimit.Limiter,
+	ipLimiter *ratelimit.Limiter,
+	wg *sync.WaitGroup) *Server {
+	return &Server{
+		config:        config,
+		logger:        logger,
+		service:       service,
+		authenticator: authenticator,
+		limiter:       limiter,
+		ipLimiter:     ipLimiter,
+		wg:            wg,
+		shutdown:      make(chan struct{}),
+	}
+}
+
+// Serve runs the gRPC server until the context is canceled, along with the standard
+// health service and the server reflection.
+//
+// On shutdown the health service reports NOT_SERVING, the streams are told to end
+// and the calls in flight are given shutdownTimeout to complete.
+func (s *Server) Serve(ctx context.Context) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.config.GrpcServer.Port))
+	if err != nil {
+		return err
+	}
+
+	srv := grpc.NewServer(
+		grpc.MaxRecvMsgSize(int(s.config.Validation.MaxBodyBytes)),
+		grpc.ChainUnaryInterceptor(s.recoverUnary, s.instrumentUnary, s.rateLimitPeerUnary, s.authorizeUnary, s.rateLimitClientUnary),
+		grpc.ChainStreamInterceptor(s.recoverStream, s.instrumentStream, s.rateLimitPeerStream, s.authorizeStream, s.rateLimitClientStream),
+	)
+	predictv1.RegisterPredictServiceServer(srv, s)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(predictv1.PredictService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, healthServer)
+
+	reflection.Register(srv)
+
+	go func() {
+		s.logger.Info("starting grpc server", "addr", listener.Addr().String())
+		if err := srv.Serve(listener); err != nil {
+			s.logger.Error(fmt.Sprintf("error grpc server: %v", err))
+		}
+	}()
+
+	<-ctx.Done()
+
+	healthServer.Shutdown()
+	close(s.shutdown)
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		srv.Stop()
+	}
+	return nil
+}
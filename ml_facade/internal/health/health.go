@@ -0,0 +1,99 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Overall statuses of a report.
+const (
+	StatusAvailable   = "available"
+	StatusDegraded    = "degraded"
+	StatusUnavailable = "unavailable"
+)
+
+// Statuses of a single dependency.
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Check probes a dependency of the application. The application cannot serve
+// requests while a critical dependency is down.
+type Check struct {
+	Name     string
+	Critical bool
+	Probe    func(ctx context.Context) error
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report aggregates the results of every check.
+type Report struct {
+	Status       string            `json:"status"`
+	Dependencies map[string]Result `json:"dependencies"`
+}
+
+// Ready returns true when no critical dependency is down.
+func (r Report) Ready() bool {
+	return r.Status != StatusUnavailable
+}
+
+// Run executes the checks concurrently, each one bounded by the given timeout.
+func Run(ctx context.Context, checks []Check, timeout time.Duration) Report {
+	results := make([]Result, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = run(ctx, check, timeout)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusAvailable, Dependencies: make(map[string]Result, len(checks))}
+	for i, check := range checks {
+		result := results[i]
+		report.Dependencies[check.Name] = result
+
+		if result.Status == StatusUp {
+			continue
+		}
+		if check.Critical {
+			report.Status = StatusUnavailable
+		} else if report.Status == StatusAvailable {
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+func run(ctx context.Context, check Check, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Probe(ctx)
+	latency := time.Since(start)
+
+	result := Result{
+		Status:    StatusUp,
+		Critical:  check.Critical,
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,93 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func up(context.Context) error {
+	return nil
+}
+
+func down(context.Context) error {
+	return errors.New("connection refused")
+}
+
+func TestRun(t *testing.T) {
+	// Test Case 1: Every dependency is up
+	t.Run("All Up", func(t *testing.T) {
+		// Arrange
+		checks := []Check{
+			{Name: "postgres", Critical: true, Probe: up},
+			{Name: "rabbitmq", Critical: false, Probe: up},
+		}
+
+		// Act
+		report := Run(context.Background(), checks, time.Second)
+
+		// Assert
+		if report.Status != StatusAvailable || !report.Ready() {
+			t.Errorf("expected status '%s', got '%s'", StatusAvailable, report.Status)
+		}
+		if len(report.Dependencies) != 2 {
+			t.Errorf("expected 2 dependencies, got %d", len(report.Dependencies))
+		}
+	})
+
+	// Test Case 2: A non-critical dependency is down
+	t.Run("Non-Critical Down", func(t *testing.T) {
+		// Arrange
+		checks := []Check{
+			{Name: "postgres", Critical: true, Probe: up},
+			{Name: "rabbitmq", Critical: false, Probe: down},
+		}
+
+		// Act
+		report := Run(context.Background(), checks, time.Second)
+
+		// Assert
+		if report.Status != StatusDegraded || !report.Ready() {
+			t.Errorf("expected status '%s', got '%s'", StatusDegraded, report.Status)
+		}
+		if report.Dependencies["rabbitmq"].Error == "" {
+			t.Error("expected the rabbitmq error to be reported")
+		}
+	})
+
+	// Test Case 3: A critical dependency is down
+	t.Run("Critical Down", func(t *testing.T) {
+		// Arrange
+		checks := []Check{
+			{Name: "postgres", Critical: true, Probe: down},
+			{Name: "rabbitmq", Critical: false, Probe: down},
+		}
+
+		// Act
+		report := Run(context.Background(), checks, time.Second)
+
+		// Assert
+		if report.Status != StatusUnavailable || report.Ready() {
+			t.Errorf("expected status '%s', got '%s'", StatusUnavailable, report.Status)
+		}
+	})
+
+	// Test Case 4: A probe exceeding the timeout is reported as down
+	t.Run("Timeout", func(t *testing.T) {
+		// Arrange
+		slow := func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		checks := []Check{{Name: "ml_service", Critical: true, Probe: slow}}
+
+		// Act
+		report := Run(context.Background(), checks, 10*time.Millisecond)
+
+		// Assert
+		if report.Dependencies["ml_service"].Status != StatusDown {
+			t.Errorf("expected status '%s', got '%s'", StatusDown, report.Dependencies["ml_service"].Status)
+		}
+	})
+}
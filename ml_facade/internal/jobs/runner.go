@@ -0,0 +1,229 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"ml_facade/config"
+	"ml_facade/internal/models/postgres_models"
+	"ml_facade/internal/service"
+	"sync"
+	"time"
+)
+
+// origin is the origin of the records stored by the predict jobs.
+const origin = "job"
+
+// Runner processes the predict jobs in the background. Jobs are stored in the
+// database, so the jobs left unfinished by a stopped replica are resumed, from their
+// first unprocessed chunk, once their lease expires.
+type Runner struct {
+	config   config.CfgJobs
+	logger   *slog.Logger
+	service  *service.MlService
+	jobModel *postgres_models.JobModel
+	wg       *sync.WaitGroup
+}
+
+func NewRunner(
+	cfg config.CfgJobs,
+	logger *slog.Logger,
+	service *service.MlService,
+	jobModel *postgres_models.JobModel,
+	wg *sync.WaitGroup) *Runner {
+	return &Runner{
+		config:   cfg,
+		logger:   logger,
+		service:  service,
+		jobModel: jobModel,
+		wg:       wg,
+	}
+}
+
+// Run starts the workers and waits for them to stop once the context is done.
+func (r *Runner) Run(ctx context.Context) {
+	var workers sync.WaitGroup
+	for i := 0; i < r.config.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			r.work(ctx)
+		}()
+	}
+	workers.Wait()
+}
+
+// work processes the jobs one after the other, polling for new jobs when there are none.
+func (r *Runner) work(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, err := r.jobModel.Claim(ctx, r.config.Lease)
+		if err != nil && ctx.Err() == nil {
+			r.logger.Error(fmt.Sprintf("error claiming predict job: %v", err))
+		}
+
+		if job != nil {
+			r.process(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// process scores the remaining chunks of a job and records the outcome of each one.
+// A chunk that fails is recorded as an error and the job moves on to the next chunk,
+// except while the ml service is unavailable, in which case the chunk waits for it.
+//
+// On shutdown, the job is released after its current chunk to be resumed later. The
+// job is dropped as soon as it turns out to have been claimed by another worker, its
+// lease having expired.
+func (r *Runner) process(ctx context.Context, job *postgres_models.Job) {
+	// The job model is still updated once the context is done
+	persistCtx := context.WithoutCancel(ctx)
+	failed := len(job.Errors)
+
+	for chunk := job.ProcessedChunks; chunk < job.TotalChunks; chunk++ {
+		from, to := chunkBounds(chunk, job.ChunkSize, job.TotalReadings)
+
+		result, err := r.processChunk(ctx, job, from, job.Readings[from:to])
+		if errors.Is(err, context.Canceled) {
+			r.release(persistCtx, job)
+			return
+		}
+		if errors.Is(err, postgres_models.ErrJobLost) {
+			r.logLost(job)
+			return
+		}
+
+		outcome := postgres_models.JobChunk{
+			Predictions: offsetPredictions(result.Predictions, from),
+			Duplicates:  offsetDuplicates(result.Duplicates, from),
+		}
+		if err != nil {
+			failed++
+			outcome.Error = &postgres_models.JobError{Chunk: chunk, From: from, To: to - 1, Error: err.Error()}
+			var validationError *service.ValidationError
+			if errors.As(err, &validationError) {
+				outcome.Error.Details = validationError.Errors
+			}
+			r.logger.Warn(fmt.Sprintf("chunk %d of predict job %d failed: %v", chunk, job.ID, err))
+		}
+
+		err = r.jobModel.SaveChunk(persistCtx, job, chunk, outcome, r.config.Lease)
+		if errors.Is(err, postgres_models.ErrJobLost) {
+			r.logLost(job)
+			return
+		}
+		if err != nil {
+			// The job is resumed from this chunk once its lease expires: the readings scored
+			// here are then listed as duplicates, see service.JobChunk
+			r.logger.Error(fmt.Sprintf("error saving chunk %d of predict job %d: %v", chunk, job.ID, err))
+			return
+		}
+
+		if ctx.Err() != nil {
+			r.release(persistCtx, job)
+			return
+		}
+	}
+
+	status := postgres_models.JobCompleted
+	if failed == job.TotalChunks {
+		status = postgres_models.JobFailed
+	}
+	err := r.jobModel.Finish(persistCtx, job, status)
+	if errors.Is(err, postgres_models.ErrJobLost) {
+		r.logLost(job)
+		return
+	}
+	if err != nil {
+		r.logger.Error(fmt.Sprintf("error finishing predict job %d: %v", job.ID, err))
+		return
+	}
+	r.logger.Info(fmt.Sprintf("predict job %d %s", job.ID, status))
+}
+
+// processChunk sends a chunk of readings to the ml service, waiting for it while it
+// is unavailable. context.Canceled is returned if the context is done while waiting, and
+// postgres_models.ErrJobLost if the job was claimed by another worker in the meantime.
+func (r *Runner) processChunk(ctx context.Context, job *postgres_models.Job, from int, readings []json.RawMessage) (service.Result, error) {
+	body, err := json.Marshal(readings)
+	if err != nil {
+		return service.Result{}, err
+	}
+
+	for {
+		// A chunk sent to the ml service is processed to completion on shutdown
+		r.wg.Add(1)
+		chunk := service.JobChunk{JobID: job.ID, Offset: from, Body: bytes.NewReader(body)}
+		result, err := r.service.HandleMlServiceRequest(context.WithoutCancel(ctx), chunk, origin)
+		if !errors.Is(err, service.ErrMlServiceUnavailable) {
+			return result, err
+		}
+
+		// Keep the lease of the job while waiting for the ml service
+		waitCtx, cancel := context.WithTimeout(ctx, r.config.Lease/2)
+		err = r.service.WaitAvailable(waitCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return service.Result{}, context.Canceled
+		}
+		if err != nil {
+			err = r.jobModel.Extend(ctx, job, r.config.Lease)
+			if errors.Is(err, postgres_models.ErrJobLost) {
+				return service.Result{}, err
+			}
+			if err != nil {
+				r.logger.Error(fmt.Sprintf("error extending the lease of predict job %d: %v", job.ID, err))
+			}
+		}
+	}
+}
+
+func (r *Runner) release(ctx context.Context, job *postgres_models.Job) {
+	err := r.jobModel.Release(ctx, job)
+	if errors.Is(err, postgres_models.ErrJobLost) {
+		r.logLost(job)
+		return
+	}
+	if err != nil {
+		r.logger.Error(fmt.Sprintf("error releasing predict job %d: %v", job.ID, err))
+	}
+}
+
+func (r *Runner) logLost(job *postgres_models.Job) {
+	r.logger.Warn(fmt.Sprintf("predict job %d was claimed by another worker, dropping it", job.ID))
+}
+
+// chunkBounds returns the indices of the first reading of a chunk and of the reading
+// following its last one.
+func chunkBounds(chunk, chunkSize, total int) (int, int) {
+	from := chunk * chunkSize
+	return from, min(from+chunkSize, total)
+}
+
+// offsetPredictions turns the indices of the predictions of a chunk into indices in the job.
+func offsetPredictions(predictions []service.Prediction, offset int) []service.Prediction {
+	for i := range predictions {
+		predictions[i].Index += offset
+	}
+	return predictions
+}
+
+// offsetDuplicates turns the indices of the duplicates of a chunk into indices in the job.
+func offsetDuplicates(duplicates []service.Duplicate, offset int) []service.Duplicate {
+	for i := range duplicates {
+		duplicates[i].Index += offset
+	}
+	return duplicates
+}
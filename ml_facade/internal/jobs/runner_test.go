@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"testing"
+
+	"ml_facade/internal/service"
+)
+
+func TestChunkBounds(t *testing.T) {
+	tests := map[string]struct {
+		chunk    int
+		from, to int
+	}{
+		"first chunk":  {0, 0, 3},
+		"middle chunk": {1, 3, 6},
+		"last chunk":   {2, 6, 7},
+	}
+
+	for name, tt := range tests {
+		// Act
+		from, to := chunkBounds(tt.chunk, 3, 7)
+
+		// Assert
+		if from != tt.from || to != tt.to {
+			t.Errorf("%s: expected [%d, %d), got [%d, %d)", name, tt.from, tt.to, from, to)
+		}
+	}
+}
+
+func TestOffset(t *testing.T) {
+	// Test Case 1: Prediction indices are shifted to their position in the job
+	t.Run("Predictions", func(t *testing.T) {
+		// Act
+		predictions := offsetPredictions([]service.Prediction{{Index: 0}, {Index: 2}}, 500)
+
+		// Assert
+		if predictions[0].Index != 500 || predictions[1].Index != 502 {
+			t.Errorf("expected indices 500 and 502, got %+v", predictions)
+		}
+	})
+
+	// Test Case 2: Duplicate indices are shifted to their position in the job
+	t.Run("Duplicates", func(t *testing.T) {
+		// Act
+		duplicates := offsetDuplicates([]service.Duplicate{{Index: 1}}, 500)
+
+		// Assert
+		if duplicates[0].Index != 501 {
+			t.Errorf("expected index 501, got %+v", duplicates)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+// Package logging correlates the log lines of an HTTP request, or of a batch of the
+// RabbitMQ consumer, through an ID carried by the context.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+type contextKey string
+
+const (
+	requestIDContextKey = contextKey("requestID")
+	batchIDContextKey   = contextKey("batchID")
+)
+
+// NewID returns a random ID.
+func NewID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithRequestID returns a copy of the context holding the ID of the HTTP request.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, id)
+}
+
+// RequestID returns the ID of the HTTP request held by the context, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// WithBatchID returns a copy of the context holding the ID of a consumer batch.
+func WithBatchID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, batchIDContextKey, id)
+}
+
+// BatchID returns the ID of the consumer batch held by the context, if any.
+func BatchID(ctx context.Context) string {
+	id, _ := ctx.Value(batchIDContextKey).(string)
+	return id
+}
+
+// Handler adds the request ID and the batch ID held by the context to the records
+// logged with it, through the *Context methods of slog.Logger.
+type Handler struct {
+	slog.Handler
+}
+
+// NewHandler wraps a handler to add the IDs of the context to its records.
+func NewHandler(handler slog.Handler) *Handler {
+	return &Handler{Handler: handler}
+}
+
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if id := BatchID(ctx); id != "" {
+		record.AddAttrs(slog.String("batch_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{Handler: h.Handler.WithGroup(name)}
+}
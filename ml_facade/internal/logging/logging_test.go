@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewJSONHandler(&buf, nil))).With("component", "test")
+	ctx := WithBatchID(WithRequestID(context.Background(), "request-1"), "batch-1")
+
+	// Act
+	logger.InfoContext(ctx, "with ids")
+	logger.Info("without ids")
+
+	// Assert
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+
+	var withIDs, withoutIDs map[string]any
+	if err := json.Unmarshal(lines[0], &withIDs); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(lines[1], &withoutIDs); err != nil {
+		t.Fatal(err)
+	}
+	if withIDs["request_id"] != "request-1" || withIDs["batch_id"] != "batch-1" || withIDs["component"] != "test" {
+		t.Errorf("expected the IDs of the context to be logged, got %v", withIDs)
+	}
+	if _, ok := withoutIDs["request_id"]; ok {
+		t.Errorf("expected no request ID without a context, got %v", withoutIDs)
+	}
+}
@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+const namespace = "ml_facade"
+
+// HTTP API
+var (
+	HttpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests handled, per route and status code.",
+	}, []string{"method", "route", "status"})
+
+	HttpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the HTTP requests, per route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	IdempotentReplays = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "idempotent_replays_total",
+		Help:      "Number of responses replayed for a request sent again with the same idempotency key.",
+	})
+)
+
+// Rate limiting
+var (
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "rate_limiter",
+		Name:      "limited_total",
+		Help:      "Number of requests refused by the ip and client rate limiters, and of readings refused by the machine rate limiter.",
+	}, []string{"limiter"})
+)
+
+// gRPC API
+var (
+	GrpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "Number of gRPC calls handled, per method and status code.",
+	}, []string{"method", "code"})
+
+	GrpcRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the gRPC calls, per method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+// ML service
+var (
+	MlServiceRequestDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "ml_service",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the calls to the ml service.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	MlServiceErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ml_service",
+		Name:      "errors_total",
+		Help:      "Number of failed calls to the ml service, per kind of error.",
+	}, []string{"kind"})
+)
+
+// PostgreSQL
+var (
+	PostgresInsertDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "postgres",
+		Name:      "insert_duration_seconds",
+		Help:      "Latency of the batch inserts into the monitoring table.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	PostgresInsertFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "postgres",
+		Name:      "insert_failures_total",
+		Help:      "Number of failed batch inserts into the monitoring table.",
+	})
+)
+
+// Readings
+var (
+	LateReadings = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "readings",
+		Name:      "late_total",
+		Help:      "Number of readings received after the lateness tolerance of their machine.",
+	})
+	DuplicateReadings = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "readings",
+		Name:      "duplicate_total",
+		Help:      "Number of readings discarded because they were already ingested.",
+	})
+)
+
+// Redis
+var (
+	RedisCounterDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "redis",
+		Name:      "counter_operation_duration_seconds",
+		Help:      "Latency of the anomaly counter operations, per operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation"})
+)
+
+// RabbitMQ
+var (
+	RabbitMQBatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "rabbitmq",
+		Name:      "batch_size",
+		Help:      "Number of messages per processed batch.",
+		Buckets:   []float64{1, 2, 5, 10, 20, 50, 100, 200, 500},
+	})
+
+	RabbitMQBusyWorkers = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "rabbitmq",
+		Name:      "busy_workers",
+		Help:      "Number of workers currently processing a batch.",
+	})
+
+	RabbitMQRetriedMessages = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "rabbitmq",
+		Name:      "retried_messages_total",
+		Help:      "Number of messages scheduled for a retry.",
+	})
+
+	RabbitMQDroppedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "rabbitmq",
+		Name:      "dropped_messages_total",
+		Help:      "Number of messages dead-lettered, per reason.",
+	}, []string{"reason"})
+)
+
+// Machines
+var (
+	MachineAnomalies = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "machine",
+		Name:      "anomalies_total",
+		Help:      "Number of readings flagged as anomalies, per machine with a threshold.",
+	}, []string{"machine_id"})
+
+	// Every machine adds a series per bucket, so only the machines with a threshold
+	// are observed, like for MachineAnomalies
+	MachineReconstructionError = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "machine",
+		Name:      "reconstruction_error",
+		Help:      "Reconstruction errors computed by the ml service, per machine with a threshold.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 16),
+	}, []string{"machine_id"})
+)
+
+// Handler returns the HTTP handler exposing the metrics in the Prometheus format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,170 @@
+package postgres_models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Scopes of the API keys. The admin scope grants every other scope, along with the
+// management of the keys.
+const (
+	ScopePredictWrite   = "predict:write"
+	ScopePredictRead    = "predict:read"
+	ScopeThresholdWrite = "threshold:write"
+	ScopeRecordsRead    = "records:read"
+	ScopeMetricsRead    = "metrics:read"
+	ScopeAdmin          = "admin"
+)
+
+// AllScopes lists the scopes that can be granted to a key.
+var AllScopes = []string{ScopePredictWrite, ScopePredictRead, ScopeThresholdWrite, ScopeRecordsRead, ScopeMetricsRead, ScopeAdmin}
+
+// apiKeyPrefixLength is the number of characters of a key stored in clear to identify it.
+const apiKeyPrefixLength = 8
+
+// Scopes are the scopes granted to an API key.
+type Scopes []string
+
+// Include reports whether the scopes grant the given scope.
+func (s Scopes) Include(scope string) bool {
+	return slices.Contains(s, scope) || slices.Contains(s, ScopeAdmin)
+}
+
+// ApiKey is a key authenticating the clients of the API. Only the hash of the key is
+// stored: the plaintext key is returned once, when the key is created.
+type ApiKey struct {
+	ID         int64      `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     Scopes     `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type ApiKeyModel struct {
+	PostgresDB *pgxpool.Pool
+}
+
+const apiKeyColumns = "id, created_at, name, prefix, scopes, expires_at, revoked_at, last_used_at"
+
+// GenerateApiKey returns a new random key along with its hash.
+func GenerateApiKey() (string, []byte, error) {
+	randomBytes := make([]byte, 32)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	plaintext := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+	return plaintext, HashApiKey(plaintext), nil
+}
+
+// HashApiKey returns the hash under which a key is stored.
+func HashApiKey(plaintext string) []byte {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hash[:]
+}
+
+// Insert creates a key with the given scopes and returns it along with its plaintext.
+// The key never expires if expiresAt is nil.
+func (k *ApiKeyModel) Insert(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (ApiKey, string, error) {
+	plaintext, hash, err := GenerateApiKey()
+	if err != nil {
+		return ApiKey{}, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	row := k.PostgresDB.QueryRow(ctx, `
+		INSERT INTO api_keys (name, hash, prefix, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+apiKeyColumns,
+		name, hash, plaintext[:apiKeyPrefixLength], scopes, expiresAt)
+	key, err := scanApiKey(row)
+	return key, plaintext, err
+}
+
+// GetForKey returns the key matching a plaintext key and records its use. It returns
+// ErrRecordNotFound if there is no such key, or if it expired or was revoked.
+func (k *ApiKeyModel) GetForKey(ctx context.Context, plaintext string) (ApiKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	row := k.PostgresDB.QueryRow(ctx, `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE hash = $1
+		AND revoked_at IS NULL
+		AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING `+apiKeyColumns,
+		HashApiKey(plaintext))
+	key, err := scanApiKey(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ApiKey{}, ErrRecordNotFound
+	}
+	return key, err
+}
+
+// List returns every key, revoked and expired ones included, the most recent first.
+func (k *ApiKeyModel) List(ctx context.Context) ([]ApiKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := k.PostgresDB.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []ApiKey{}
+	for rows.Next() {
+		key, err := scanApiKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke revokes a key. It returns ErrRecordNotFound if the key does not exist or
+// was already revoked.
+func (k *ApiKeyModel) Revoke(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := k.PostgresDB.Exec(ctx, `
+		UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func scanApiKey(row pgx.Row) (ApiKey, error) {
+	var key ApiKey
+	err := row.Scan(
+		&key.ID,
+		&key.CreatedAt,
+		&key.Name,
+		&key.Prefix,
+		&key.Scopes,
+		&key.ExpiresAt,
+		&key.RevokedAt,
+		&key.LastUsedAt,
+	)
+	return key, err
+}
@@ -0,0 +1,266 @@
+package postgres_models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+// ErrRecordNotFound is returned when the requested row does not exist.
+var ErrRecordNotFound = errors.New("record not found")
+
+// ErrJobLost is returned when a job is updated by a worker whose lease expired and whose
+// job was claimed by another worker in the meantime.
+var ErrJobLost = errors.New("predict job claimed by another worker")
+
+// Statuses of a predict job. A job is queued until a worker picks it up, and running
+// until all its chunks were processed.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// JobError describes a chunk of a job that could not be processed. From and To are
+// the indices of the first and last readings of the chunk in the job.
+type JobError struct {
+	Chunk   int               `json:"chunk"`
+	From    int               `json:"from"`
+	To      int               `json:"to"`
+	Error   string            `json:"error"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Job is a batch of readings scored in the background, chunk by chunk. Predictions and
+// Duplicates accumulate the results of the processed chunks. Readings are only loaded
+// by the workers processing the job.
+type Job struct {
+	ID              int64             `json:"id"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	Status          string            `json:"status"`
+	TotalReadings   int               `json:"total_readings"`
+	ChunkSize       int               `json:"chunk_size"`
+	TotalChunks     int               `json:"total_chunks"`
+	ProcessedChunks int               `json:"processed_chunks"`
+	Predictions     json.RawMessage   `json:"predictions"`
+	Duplicates      json.RawMessage   `json:"duplicates"`
+	Errors          []JobError        `json:"errors"`
+	Readings        []json.RawMessage `json:"-"`
+	// ApiKeyID is the ID of the key that created the job, nil if authentication is disabled
+	ApiKeyID *int64 `json:"-"`
+	// LeaseToken identifies the claim of a running job, see Claim
+	LeaseToken int64 `json:"-"`
+}
+
+// JobChunk is the outcome of a chunk of a job. Error is nil if the chunk succeeded.
+type JobChunk struct {
+	Predictions any
+	Duplicates  any
+	Error       *JobError
+}
+
+type JobModel struct {
+	PostgresDB *pgxpool.Pool
+}
+
+const jobColumns = "id, created_at, updated_at, status, total_readings, chunk_size, processed_chunks, predictions, duplicates, errors, api_key_id"
+
+// Insert queues a job for the given readings, split in chunks of chunkSize readings, on
+// behalf of the given API key, nil if authentication is disabled.
+func (j *JobModel) Insert(ctx context.Context, readings []json.RawMessage, chunkSize int, apiKeyID *int64) (Job, error) {
+	data, err := json.Marshal(readings)
+	if err != nil {
+		return Job{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	row := j.PostgresDB.QueryRow(ctx, `
+		INSERT INTO predict_jobs (readings, total_readings, chunk_size, api_key_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+jobColumns,
+		data, len(readings), chunkSize, apiKeyID)
+	return scanJob(row)
+}
+
+// Get returns a job, without its readings.
+func (j *JobModel) Get(ctx context.Context, id int64) (Job, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	row := j.PostgresDB.QueryRow(ctx, `SELECT `+jobColumns+` FROM predict_jobs WHERE id = $1`, id)
+	job, err := scanJob(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Job{}, ErrRecordNotFound
+	}
+	return job, err
+}
+
+// Claim picks the oldest job left to process, along with its readings, and leases it to
+// the caller for the given duration. Running jobs whose lease expired, because their
+// worker stopped, are picked up again. It returns nil if there is no job to process.
+//
+// Each claim gives the job a new lease token, which fences the updates of the job: those
+// made with the token of a previous claim fail with ErrJobLost.
+func (j *JobModel) Claim(ctx context.Context, lease time.Duration) (*Job, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	row := j.PostgresDB.QueryRow(ctx, `
+		UPDATE predict_jobs
+		SET status = $1, locked_until = NOW() + $2 * interval '1 millisecond', updated_at = NOW(),
+			lease_token = lease_token + 1
+		WHERE id = (
+			SELECT id FROM predict_jobs
+			WHERE status IN ($3, $1)
+			AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING `+jobColumns+`, readings, lease_token`,
+		JobRunning, lease.Milliseconds(), JobQueued)
+
+	var job Job
+	err := row.Scan(append(jobTargets(&job), &job.Readings, &job.LeaseToken)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	job.TotalChunks = totalChunks(job)
+	return &job, nil
+}
+
+// SaveChunk records the outcome of the given chunk of a job and extends its lease. It
+// returns ErrJobLost if the job was claimed by another worker, or if the chunk was
+// already recorded.
+func (j *JobModel) SaveChunk(ctx context.Context, job *Job, index int, chunk JobChunk, lease time.Duration) error {
+	predictions, err := marshalArray(chunk.Predictions)
+	if err != nil {
+		return err
+	}
+	duplicates, err := marshalArray(chunk.Duplicates)
+	if err != nil {
+		return err
+	}
+	jobErrors := []JobError{}
+	if chunk.Error != nil {
+		jobErrors = append(jobErrors, *chunk.Error)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := j.PostgresDB.Exec(ctx, `
+		UPDATE predict_jobs
+		SET processed_chunks = processed_chunks + 1,
+			predictions = predictions || $4::jsonb,
+			duplicates = duplicates || $5::jsonb,
+			errors = errors || $6::jsonb,
+			locked_until = NOW() + $7 * interval '1 millisecond',
+			updated_at = NOW()
+		WHERE id = $1 AND lease_token = $2 AND processed_chunks = $3`,
+		job.ID, job.LeaseToken, index, predictions, duplicates, jobErrors, lease.Milliseconds())
+	return checkLease(result, err)
+}
+
+// Extend extends the lease of a running job. It returns ErrJobLost if the job was
+// claimed by another worker.
+func (j *JobModel) Extend(ctx context.Context, job *Job, lease time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := j.PostgresDB.Exec(ctx, `
+		UPDATE predict_jobs SET locked_until = NOW() + $3 * interval '1 millisecond'
+		WHERE id = $1 AND lease_token = $2`,
+		job.ID, job.LeaseToken, lease.Milliseconds())
+	return checkLease(result, err)
+}
+
+// Release gives up the lease of a running job, so that it is resumed by the next worker.
+// It returns ErrJobLost if the job was claimed by another worker.
+func (j *JobModel) Release(ctx context.Context, job *Job) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := j.PostgresDB.Exec(ctx, `
+		UPDATE predict_jobs SET locked_until = NULL WHERE id = $1 AND lease_token = $2`,
+		job.ID, job.LeaseToken)
+	return checkLease(result, err)
+}
+
+// Finish sets the final status of a job and drops its readings, which are not needed anymore.
+// It returns ErrJobLost if the job was claimed by another worker.
+func (j *JobModel) Finish(ctx context.Context, job *Job, status string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := j.PostgresDB.Exec(ctx, `
+		UPDATE predict_jobs
+		SET status = $3, readings = '[]', locked_until = NULL, updated_at = NOW()
+		WHERE id = $1 AND lease_token = $2`,
+		job.ID, job.LeaseToken, status)
+	return checkLease(result, err)
+}
+
+// checkLease turns an update of a job that matched no row into ErrJobLost.
+func checkLease(result pgconn.CommandTag, err error) error {
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrJobLost
+	}
+	return nil
+}
+
+func jobTargets(job *Job) []any {
+	return []any{
+		&job.ID,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.Status,
+		&job.TotalReadings,
+		&job.ChunkSize,
+		&job.ProcessedChunks,
+		&job.Predictions,
+		&job.Duplicates,
+		&job.Errors,
+		&job.ApiKeyID,
+	}
+}
+
+func scanJob(row pgx.Row) (Job, error) {
+	var job Job
+	err := row.Scan(jobTargets(&job)...)
+	if err != nil {
+		return Job{}, err
+	}
+	job.TotalChunks = totalChunks(job)
+	return job, nil
+}
+
+// marshalArray encodes a slice, nil slices being encoded as an empty array.
+func marshalArray(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || string(data) != "null" {
+		return data, err
+	}
+	return []byte("[]"), nil
+}
+
+func totalChunks(job Job) int {
+	if job.ChunkSize == 0 {
+		return 0
+	}
+	return (job.TotalReadings + job.ChunkSize - 1) / job.ChunkSize
+}
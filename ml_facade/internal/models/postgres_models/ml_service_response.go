@@ -15,5 +15,6 @@ type Record struct {
 	ReconstructionError float64
 	Anomaly             bool
 	AnomalyCounter      int
+	Late                bool
 	Origin              string
 }
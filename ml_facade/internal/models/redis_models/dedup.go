@@ -0,0 +1,134 @@
+package redis_models
+
+import (
+	"context"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"time"
+)
+
+// DedupModel remembers the readings that were already ingested, so that a reading
+// sent twice is only scored and stored once.
+type DedupModel struct {
+	RedisDB *redis.Pool
+}
+
+func dedupKey(key string) string {
+	return fmt.Sprintf("dedup:%s", key)
+}
+
+// ClaimStatus is the outcome of claiming a deduplication key.
+type ClaimStatus int
+
+const (
+	// Claimed means the key was claimed by this call.
+	Claimed ClaimStatus = iota
+	// Seen means the reading of the key was already ingested, or appears earlier in the
+	// keys claimed together.
+	Seen
+	// InProgress means the reading of the key is being processed by another call, which
+	// may still fail.
+	InProgress
+)
+
+// dedupPending is the value of a key claimed by a reading still being processed, while
+// the keys of the readings ingested hold 1.
+const dedupPending = "pending"
+
+// claimScript claims every key that is not set yet, with the pending value given as
+// ARGV[2] for the timeout given as ARGV[1] in milliseconds. It returns the ClaimStatus
+// of each key.
+var claimScript = redis.NewScript(-1, `
+	local statuses = {}
+	local claimed = {}
+	for i, key in ipairs(KEYS) do
+		if claimed[key] then
+			statuses[i] = 1
+		elseif redis.call("SET", key, ARGV[2], "NX", "PX", ARGV[1]) then
+			claimed[key] = true
+			statuses[i] = 0
+		elseif redis.call("GET", key) == ARGV[2] then
+			statuses[i] = 2
+		else
+			statuses[i] = 1
+		end
+	end
+	return statuses
+`)
+
+// Claim marks the keys as being processed for the given timeout, after which they
+// are forgotten unless Commit was called. It returns the status of each key.
+func (d *DedupModel) Claim(ctx context.Context, keys []string, timeout time.Duration) ([]ClaimStatus, error) {
+	statuses := make([]ClaimStatus, len(keys))
+	if len(keys) == 0 {
+		return statuses, nil
+	}
+
+	conn, err := d.RedisDB.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	args := make([]any, 0, len(keys)+3)
+	args = append(args, len(keys))
+	for _, key := range keys {
+		args = append(args, dedupKey(key))
+	}
+	args = append(args, timeout.Milliseconds(), dedupPending)
+
+	replies, err := redis.Ints(claimScript.DoContext(ctx, conn, args...))
+	if err != nil {
+		return nil, err
+	}
+	for i, reply := range replies {
+		statuses[i] = ClaimStatus(reply)
+	}
+	return statuses, nil
+}
+
+// Commit marks the claimed keys as ingested for the given window, once their readings
+// were stored.
+func (d *DedupModel) Commit(ctx context.Context, keys []string, window time.Duration) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	conn, err := d.RedisDB.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, key := range keys {
+		err = conn.Send("SET", dedupKey(key), 1, "PX", window.Milliseconds())
+		if err != nil {
+			return err
+		}
+	}
+
+	// Flush the pipeline and wait for every reply
+	_, err = redis.DoContext(conn, ctx, "")
+	return err
+}
+
+// Release forgets the keys, so that the readings can be ingested again. It is used
+// when the readings could not be processed after being claimed.
+func (d *DedupModel) Release(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	conn, err := d.RedisDB.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	args := make([]any, len(keys))
+	for i, key := range keys {
+		args[i] = dedupKey(key)
+	}
+	_, err = redis.DoContext(conn, ctx, "DEL", args...)
+	return err
+}
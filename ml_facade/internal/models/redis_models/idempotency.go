@@ -0,0 +1,106 @@
+package redis_models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"time"
+)
+
+// ErrIdempotencyInProgress is returned while another request with the same idempotency key is processed.
+var ErrIdempotencyInProgress = errors.New("a request with the same idempotency key is being processed")
+
+// IdempotentResponse is the response stored for an idempotency key. Fingerprint identifies
+// the request the key was first used with. Status is zero while the request is processed.
+type IdempotentResponse struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// IdempotencyModel stores the responses of the requests sent with an idempotency key,
+// so that a retried request gets the original response instead of being processed again.
+type IdempotencyModel struct {
+	RedisDB *redis.Pool
+}
+
+func idempotencyKey(key string) string {
+	return fmt.Sprintf("idempotency:%s", key)
+}
+
+// beginScript locks the key when it is unused, returning nil, or returns what it holds.
+var beginScript = redis.NewScript(1, `
+	if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+		return nil
+	end
+	return redis.call("GET", KEYS[1])
+`)
+
+// Begin locks the key for a request with the given fingerprint. The lock expires after
+// lockTimeout in case the request never completes.
+//
+// It returns nil if the request should be processed, the stored response if the key was
+// already used, or ErrIdempotencyInProgress if a request with the key is being processed.
+func (i *IdempotencyModel) Begin(ctx context.Context, key, fingerprint string, lockTimeout time.Duration) (*IdempotentResponse, error) {
+	lock, err := json.Marshal(IdempotentResponse{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := i.RedisDB.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	reply, err := redis.Bytes(beginScript.DoContext(ctx, conn, idempotencyKey(key), lock, lockTimeout.Milliseconds()))
+	if errors.Is(err, redis.ErrNil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stored IdempotentResponse
+	err = json.Unmarshal(reply, &stored)
+	if err != nil {
+		return nil, err
+	}
+	if stored.Status == 0 {
+		return nil, ErrIdempotencyInProgress
+	}
+	return &stored, nil
+}
+
+// Complete stores the response of the request holding the key, replacing its lock.
+// The response is replayed for the given window.
+func (i *IdempotencyModel) Complete(ctx context.Context, key string, response IdempotentResponse, window time.Duration) error {
+	payload, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	conn, err := i.RedisDB.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = redis.DoContext(conn, ctx, "SET", idempotencyKey(key), payload, "PX", window.Milliseconds())
+	return err
+}
+
+// Abandon releases the key without storing a response, so that the request can be retried.
+func (i *IdempotencyModel) Abandon(ctx context.Context, key string) error {
+	conn, err := i.RedisDB.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = redis.DoContext(conn, ctx, "DEL", idempotencyKey(key))
+	return err
+}
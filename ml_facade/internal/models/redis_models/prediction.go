@@ -0,0 +1,107 @@
+package redis_models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"time"
+)
+
+// PredictionEvent is a scored reading, as broadcast to the live streams of a machine.
+type PredictionEvent struct {
+	ID                  int64     `json:"id"`
+	CreatedAt           time.Time `json:"created_at"`
+	EventTime           time.Time `json:"event_time"`
+	Late                bool      `json:"late"`
+	MachineID           int       `json:"machine_id"`
+	ReconstructionError float64   `json:"reconstruction_error"`
+	Anomaly             bool      `json:"anomaly"`
+	AnomalyCounter      int       `json:"anomaly_counter"`
+	Origin              string    `json:"origin"`
+}
+
+// PredictionModel broadcasts prediction events through Redis pub/sub, so every
+// facade replica can stream the readings scored by the other ones.
+type PredictionModel struct {
+	RedisDB *redis.Pool
+}
+
+func predictionChannel(machineID int) string {
+	return fmt.Sprintf("predictions:%v", machineID)
+}
+
+// Publish broadcasts the events on the channel of their machine.
+func (p *PredictionModel) Publish(ctx context.Context, events []PredictionEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	conn, err := p.RedisDB.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		err = conn.Send("PUBLISH", predictionChannel(event.MachineID), payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Flush the pipeline and wait for every reply
+	_, err = redis.DoContext(conn, ctx, "")
+	return err
+}
+
+// Subscribe listens to the events of a machine until the context is done.
+//
+// It returns once the subscription is confirmed by Redis, so no event published
+// afterwards is missed. The returned channel is closed when the subscription ends.
+func (p *PredictionModel) Subscribe(ctx context.Context, machineID int) (<-chan PredictionEvent, error) {
+	psc := redis.PubSubConn{Conn: p.RedisDB.Get()}
+
+	err := psc.Subscribe(predictionChannel(machineID))
+	if err != nil {
+		psc.Close()
+		return nil, err
+	}
+
+	switch v := psc.ReceiveContext(ctx).(type) {
+	case redis.Subscription:
+	case error:
+		psc.Close()
+		return nil, v
+	}
+
+	events := make(chan PredictionEvent, 64)
+
+	go func() {
+		defer close(events)
+		defer psc.Close()
+
+		for {
+			switch v := psc.ReceiveContext(ctx).(type) {
+			case redis.Message:
+				var event PredictionEvent
+				if err := json.Unmarshal(v.Data, &event); err != nil {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			case error:
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
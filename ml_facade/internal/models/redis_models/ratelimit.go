@@ -0,0 +1,81 @@
+package redis_models
+
+import (
+	"context"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"strconv"
+)
+
+// RateLimitModel stores token buckets shared by the replicas of the facade, so that
+// they enforce a single rate limit together.
+type RateLimitModel struct {
+	RedisDB *redis.Pool
+}
+
+func rateLimitKey(key string) string {
+	return fmt.Sprintf("ratelimit:%s", key)
+}
+
+// takeTokensScript refills the bucket for the time elapsed since its last use, then
+// spends the tokens requested if the bucket holds enough of them. The clock of Redis
+// is used so that the replicas agree on the time. The bucket expires once it would be
+// full again, since a missing bucket is a full one.
+//
+// It returns whether the tokens were spent, and the tokens left as a string since Lua
+// numbers are truncated to integers in the replies.
+var takeTokensScript = redis.NewScript(1, `
+	local key = KEYS[1]
+	local rate = tonumber(ARGV[1])
+	local burst = tonumber(ARGV[2])
+	local n = tonumber(ARGV[3])
+
+	local time = redis.call("TIME")
+	local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+	local bucket = redis.call("HMGET", key, "tokens", "updated_at")
+	local tokens = tonumber(bucket[1]) or burst
+	local updatedAt = tonumber(bucket[2]) or now
+	tokens = math.min(burst, tokens + math.max(0, now - updatedAt) * rate / 1000)
+
+	local allowed = 0
+	if n <= tokens then
+		tokens = tokens - n
+		allowed = 1
+	end
+
+	redis.call("HSET", key, "tokens", tostring(tokens), "updated_at", now)
+	redis.call("PEXPIRE", key, math.ceil((burst - tokens) * 1000 / rate) + 1000)
+	return {allowed, tostring(tokens)}
+`)
+
+// Take spends n tokens from the bucket of the key, refilled at rps tokens per second
+// and holding at most burst tokens, or none of them if the bucket does not hold enough
+// tokens. It reports whether the tokens were spent and returns the tokens left.
+//
+// rps must be positive.
+func (r *RateLimitModel) Take(ctx context.Context, key string, rps float64, burst, n int) (bool, float64, error) {
+	conn, err := r.RedisDB.GetContext(ctx)
+	if err != nil {
+		return false, 0, err
+	}
+	defer conn.Close()
+
+	reply, err := redis.Values(takeTokensScript.DoContext(ctx, conn, rateLimitKey(key), rps, burst, n))
+	if err != nil {
+		return false, 0, err
+	}
+
+	var allowed int
+	var tokens string
+	_, err = redis.Scan(reply, &allowed, &tokens)
+	if err != nil {
+		return false, 0, err
+	}
+
+	remaining, err := strconv.ParseFloat(tokens, 64)
+	if err != nil {
+		return false, 0, err
+	}
+	return allowed == 1, remaining, nil
+}
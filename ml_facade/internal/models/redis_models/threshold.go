@@ -1,50 +1,287 @@
 package redis_models
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/gomodule/redigo/redis"
+	"sort"
+	"strconv"
+	"strings"
 )
 
+var ErrRecordNotFound = errors.New("record not found")
+
 type Threshold struct {
-	MachineID int     `json:"machine_id" redis:"machine_id"`
-	Threshold float64 `json:"threshold" redis:"threshold"`
+	MachineID      int     `json:"machine_id" redis:"machine_id"`
+	Threshold      float64 `json:"threshold" redis:"threshold"`
+	AnomalyCounter int     `json:"anomaly_counter" redis:"anomaly_counter"`
 }
 
 type ThresholdModel struct {
 	RedisDB *redis.Pool
 }
 
-func (t *ThresholdModel) Insert(threshold Threshold) error {
-	conn := t.RedisDB.Get()
+func thresholdKey(id int) string {
+	return fmt.Sprintf("threshold:%v", id)
+}
+
+func anomalyCounterKey(id int) string {
+	return fmt.Sprintf("anomaly_counter:%v", id)
+}
+
+// thresholdChannel is where the machine IDs of the thresholds set or deleted are
+// published, so that every facade replica drops them from its cache.
+const thresholdChannel = "thresholds:invalidated"
+
+// Ping checks that the database answers.
+func (t *ThresholdModel) Ping(ctx context.Context) error {
+	conn, err := t.RedisDB.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = redis.DoContext(conn, ctx, "PING")
+	return err
+}
+
+// Insert sets the threshold of a machine. The anomaly counter of the machine is
+// only reset when resetCounter is true, otherwise it is initialized if missing.
+func (t *ThresholdModel) Insert(ctx context.Context, threshold Threshold, resetCounter bool) error {
+	conn, err := t.RedisDB.GetContext(ctx)
+	if err != nil {
+		return err
+	}
 	defer conn.Close()
 
-	// Initialize the threshold value
-	_, err := conn.Do("SET", fmt.Sprintf("threshold:%v", threshold.MachineID), threshold.Threshold)
+	err = conn.Send("MULTI")
 	if err != nil {
 		return err
 	}
 
-	// Initialize the anomaly counter
-	_, err = conn.Do("SET", fmt.Sprintf("anomaly_counter:%v", threshold.MachineID), 0)
+	// Set the threshold value
+	err = conn.Send("SET", thresholdKey(threshold.MachineID), threshold.Threshold)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	// Reset or initialize the anomaly counter
+	if resetCounter {
+		err = conn.Send("SET", anomalyCounterKey(threshold.MachineID), 0)
+	} else {
+		err = conn.Send("SETNX", anomalyCounterKey(threshold.MachineID), 0)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Tell the replicas in the same transaction, so that none misses the new value
+	err = conn.Send("PUBLISH", thresholdChannel, threshold.MachineID)
+	if err != nil {
+		return err
+	}
+
+	_, err = redis.DoContext(conn, ctx, "EXEC")
+	return err
 }
 
-func (t *ThresholdModel) Get(id int) (float64, error) {
-	conn := t.RedisDB.Get()
+func (t *ThresholdModel) Get(ctx context.Context, id int) (float64, error) {
+	conn, err := t.RedisDB.GetContext(ctx)
+	if err != nil {
+		return 0., err
+	}
 	defer conn.Close()
 
-	threshold, err := redis.Float64(conn.Do("GET", fmt.Sprintf("threshold:%v", id)))
+	threshold, err := redis.Float64(redis.DoContext(conn, ctx, "GET", thresholdKey(id)))
 	if err != nil {
 		return 0., err
 	}
 	return threshold, nil
 }
 
-func (t *ThresholdModel) Increment(id int) (int, error) {
-	conn := t.RedisDB.Get()
+// GetWithCounter returns the threshold of a machine along with its current anomaly counter.
+func (t *ThresholdModel) GetWithCounter(ctx context.Context, id int) (Threshold, error) {
+	thresholds, err := t.getMany(ctx, []int{id})
+	if err != nil {
+		return Threshold{}, err
+	}
+	if len(thresholds) == 0 {
+		return Threshold{}, ErrRecordNotFound
+	}
+	return thresholds[0], nil
+}
+
+// List returns the thresholds of every configured machine, ordered by machine ID.
+func (t *ThresholdModel) List(ctx context.Context) ([]Threshold, error) {
+	conn, err := t.RedisDB.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var ids []int
+	cursor := 0
+	for {
+		values, err := redis.Values(redis.DoContext(conn, ctx, "SCAN", cursor, "MATCH", "threshold:*", "COUNT", 100))
+		if err != nil {
+			return nil, err
+		}
+
+		cursor, err = redis.Int(values[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := redis.Strings(values[1], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			id, err := strconv.Atoi(strings.TrimPrefix(key, "threshold:"))
+			if err != nil {
+				continue
+			}
+			ids = append(ids, id)
+		}
+
+		if cursor == 0 {
+			break
+		}
+	}
+
+	sort.Ints(ids)
+	return t.getMany(ctx, ids)
+}
+
+// getMany fetches the thresholds and anomaly counters of the given machines.
+// Machines without a threshold are skipped.
+func (t *ThresholdModel) getMany(ctx context.Context, ids []int) ([]Threshold, error) {
+	thresholds := make([]Threshold, 0, len(ids))
+	if len(ids) == 0 {
+		return thresholds, nil
+	}
+
+	conn, err := t.RedisDB.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	args := make([]any, 0, 2*len(ids))
+	for _, id := range ids {
+		args = append(args, thresholdKey(id), anomalyCounterKey(id))
+	}
+
+	values, err := redis.Values(redis.DoContext(conn, ctx, "MGET", args...))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, id := range ids {
+		threshold, err := redis.Float64(values[2*i], nil)
+		if errors.Is(err, redis.ErrNil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		counter, err := redis.Int(values[2*i+1], nil)
+		if err != nil && !errors.Is(err, redis.ErrNil) {
+			return nil, err
+		}
+
+		thresholds = append(thresholds, Threshold{MachineID: id, Threshold: threshold, AnomalyCounter: counter})
+	}
+
+	return thresholds, nil
+}
+
+// deleteThresholdScript removes the threshold of a machine along with its anomaly counter
+// and its watermark, all at once, then publishes the machine ID on the channel given
+// as ARGV[1]. It returns 0 if the machine had no threshold.
+var deleteThresholdScript = redis.NewScript(3, `
+	if redis.call("DEL", KEYS[1]) == 0 then
+		return 0
+	end
+	redis.call("DEL", KEYS[2], KEYS[3])
+	redis.call("PUBLISH", ARGV[1], ARGV[2])
+	return 1
+`)
+
+// Delete removes the threshold and the anomaly counter of a machine.
+func (t *ThresholdModel) Delete(ctx context.Context, id int) error {
+	conn, err := t.RedisDB.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deleted, err := redis.Int(deleteThresholdScript.DoContext(ctx, conn, thresholdKey(id), anomalyCounterKey(id), watermarkKey(id), thresholdChannel, id))
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// SubscribeInvalidations listens to the machine IDs of the thresholds set or deleted,
+// by any replica, until the context is done.
+//
+// It returns once the subscription is confirmed by Redis, so no invalidation published
+// afterwards is missed. The returned channel is closed when the subscription ends,
+// after which the invalidations are missed until the next subscription.
+func (t *ThresholdModel) SubscribeInvalidations(ctx context.Context) (<-chan int, error) {
+	psc := redis.PubSubConn{Conn: t.RedisDB.Get()}
+
+	err := psc.Subscribe(thresholdChannel)
+	if err != nil {
+		psc.Close()
+		return nil, err
+	}
+
+	switch v := psc.ReceiveContext(ctx).(type) {
+	case redis.Subscription:
+	case error:
+		psc.Close()
+		return nil, v
+	}
+
+	machineIDs := make(chan int, 64)
+
+	go func() {
+		defer close(machineIDs)
+		defer psc.Close()
+
+		for {
+			switch v := psc.ReceiveContext(ctx).(type) {
+			case redis.Message:
+				machineID, err := strconv.Atoi(string(v.Data))
+				if err != nil {
+					continue
+				}
+				select {
+				case machineIDs <- machineID:
+				case <-ctx.Done():
+					return
+				}
+			case error:
+				return
+			}
+		}
+	}()
+
+	return machineIDs, nil
+}
+
+func (t *ThresholdModel) Increment(ctx context.Context, id int) (int, error) {
+	conn, err := t.RedisDB.GetContext(ctx)
+	if err != nil {
+		return 0, err
+	}
 	defer conn.Close()
 
 	script := redis.NewScript(1, `
@@ -56,7 +293,7 @@ func (t *ThresholdModel) Increment(id int) (int, error) {
 		return currentCounter
 	`)
 
-	anomalyCounter, err := redis.Int(script.Do(conn, fmt.Sprintf("anomaly_counter:%v", id)))
+	anomalyCounter, err := redis.Int(script.DoContext(ctx, conn, anomalyCounterKey(id)))
 	if err != nil {
 		return 0, err
 	}
@@ -64,8 +301,11 @@ func (t *ThresholdModel) Increment(id int) (int, error) {
 	return anomalyCounter, nil
 }
 
-func (t *ThresholdModel) Decrement(id int) (int, error) {
-	conn := t.RedisDB.Get()
+func (t *ThresholdModel) Decrement(ctx context.Context, id int) (int, error) {
+	conn, err := t.RedisDB.GetContext(ctx)
+	if err != nil {
+		return 0, err
+	}
 	defer conn.Close()
 
 	// Lua script to decrement the counter if it's above 0
@@ -78,7 +318,7 @@ func (t *ThresholdModel) Decrement(id int) (int, error) {
 		return currentCounter
 	`)
 
-	anomalyCounter, err := redis.Int(script.Do(conn, fmt.Sprintf("anomaly_counter:%v", id)))
+	anomalyCounter, err := redis.Int(script.DoContext(ctx, conn, anomalyCounterKey(id)))
 	if err != nil {
 		return 0, err
 	}
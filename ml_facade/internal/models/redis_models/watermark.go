@@ -0,0 +1,60 @@
+package redis_models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"time"
+)
+
+func watermarkKey(id int) string {
+	return fmt.Sprintf("watermark:%v", id)
+}
+
+// advanceWatermarkScript keeps the latest event time seen for a machine, in milliseconds,
+// and returns the value it held before the call.
+var advanceWatermarkScript = redis.NewScript(1, `
+	local key = KEYS[1]
+	local eventTime = tonumber(ARGV[1])
+	local watermark = tonumber(redis.call("GET", key) or "0")
+	if eventTime > watermark then
+		redis.call("SET", key, eventTime)
+	end
+	return watermark
+`)
+
+// AdvanceWatermark records the event time of a reading of the machine. It returns the
+// watermark of the machine before the reading, that is the latest event time seen so
+// far, or the zero time if the machine had no reading yet.
+func (t *ThresholdModel) AdvanceWatermark(ctx context.Context, id int, eventTime time.Time) (time.Time, error) {
+	conn, err := t.RedisDB.GetContext(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer conn.Close()
+
+	watermark, err := redis.Int64(advanceWatermarkScript.DoContext(ctx, conn, watermarkKey(id), eventTime.UnixMilli()))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if watermark == 0 {
+		return time.Time{}, nil
+	}
+	return time.UnixMilli(watermark), nil
+}
+
+// Counter returns the current anomaly counter of a machine without changing it.
+func (t *ThresholdModel) Counter(ctx context.Context, id int) (int, error) {
+	conn, err := t.RedisDB.GetContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	counter, err := redis.Int(redis.DoContext(conn, ctx, "GET", anomalyCounterKey(id)))
+	if errors.Is(err, redis.ErrNil) {
+		return 0, nil
+	}
+	return counter, err
+}
@@ -0,0 +1,197 @@
+// Schema of the binary predict requests and responses, and of the gRPC ingestion service.
+//
+// Protobuf bodies are sent with the application/x-protobuf content type, to
+// POST /v1/predict as a PredictRequest and to the AMQP queue as a single Reading.
+// The fields mirror the JSON format: see the README for their meaning.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: predict/v1/predict.proto
+
+package predictv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	PredictService_Predict_FullMethodName        = "/predict.v1.PredictService/Predict"
+	PredictService_StreamReadings_FullMethodName = "/predict.v1.PredictService/StreamReadings"
+)
+
+// PredictServiceClient is the client API for PredictService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PredictServiceClient interface {
+	// Predict scores a batch of readings. The batch is refused with INVALID_ARGUMENT
+	// if one of its readings is invalid, as with the HTTP route.
+	Predict(ctx context.Context, in *PredictRequest, opts ...grpc.CallOption) (*PredictResponse, error)
+	// StreamReadings scores the readings of the stream in micro-batches as they arrive.
+	// Invalid readings are rejected on their own, without ending the stream, and the
+	// outcome of the whole stream is sent once the client closes it.
+	StreamReadings(ctx context.Context, opts ...grpc.CallOption) (PredictService_StreamReadingsClient, error)
+}
+
+type predictServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPredictServiceClient(cc grpc.ClientConnInterface) PredictServiceClient {
+	return &predictServiceClient{cc}
+}
+
+func (c *predictServiceClient) Predict(ctx context.Context, in *PredictRequest, opts ...grpc.CallOption) (*PredictResponse, error) {
+	out := new(PredictResponse)
+	err := c.cc.Invoke(ctx, PredictService_Predict_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *predictServiceClient) StreamReadings(ctx context.Context, opts ...grpc.CallOption) (PredictService_StreamReadingsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &PredictService_ServiceDesc.Streams[0], PredictService_StreamReadings_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &predictServiceStreamReadingsClient{stream}
+	return x, nil
+}
+
+type PredictService_StreamReadingsClient interface {
+	Send(*Reading) error
+	CloseAndRecv() (*StreamReadingsResponse, error)
+	grpc.ClientStream
+}
+
+type predictServiceStreamReadingsClient struct {
+	grpc.ClientStream
+}
+
+func (x *predictServiceStreamReadingsClient) Send(m *Reading) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *predictServiceStreamReadingsClient) CloseAndRecv() (*StreamReadingsResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(StreamReadingsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// PredictServiceServer is the server API for PredictService service.
+// All implementations must embed UnimplementedPredictServiceServer
+// for forward compatibility
+type PredictServiceServer interface {
+	// Predict scores a batch of readings. The batch is refused with INVALID_ARGUMENT
+	// if one of its readings is invalid, as with the HTTP route.
+	Predict(context.Context, *PredictRequest) (*PredictResponse, error)
+	// StreamReadings scores the readings of the stream in micro-batches as they arrive.
+	// Invalid readings are rejected on their own, without ending the stream, and the
+	// outcome of the whole stream is sent once the client closes it.
+	StreamReadings(PredictService_StreamReadingsServer) error
+	mustEmbedUnimplementedPredictServiceServer()
+}
+
+// UnimplementedPredictServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedPredictServiceServer struct {
+}
+
+func (UnimplementedPredictServiceServer) Predict(context.Context, *PredictRequest) (*PredictResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Predict not implemented")
+}
+func (UnimplementedPredictServiceServer) StreamReadings(PredictService_StreamReadingsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamReadings not implemented")
+}
+func (UnimplementedPredictServiceServer) mustEmbedUnimplementedPredictServiceServer() {}
+
+// UnsafePredictServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PredictServiceServer will
+// result in compilation errors.
+type UnsafePredictServiceServer interface {
+	mustEmbedUnimplementedPredictServiceServer()
+}
+
+func RegisterPredictServiceServer(s grpc.ServiceRegistrar, srv PredictServiceServer) {
+	s.RegisterService(&PredictService_ServiceDesc, srv)
+}
+
+func _PredictService_Predict_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PredictRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PredictServiceServer).Predict(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PredictService_Predict_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PredictServiceServer).Predict(ctx, req.(*PredictRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PredictService_StreamReadings_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PredictServiceServer).StreamReadings(&predictServiceStreamReadingsServer{stream})
+}
+
+type PredictService_StreamReadingsServer interface {
+	SendAndClose(*StreamReadingsResponse) error
+	Recv() (*Reading, error)
+	grpc.ServerStream
+}
+
+type predictServiceStreamReadingsServer struct {
+	grpc.ServerStream
+}
+
+func (x *predictServiceStreamReadingsServer) SendAndClose(m *StreamReadingsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *predictServiceStreamReadingsServer) Recv() (*Reading, error) {
+	m := new(Reading)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// PredictService_ServiceDesc is the grpc.ServiceDesc for PredictService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PredictService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "predict.v1.PredictService",
+	HandlerType: (*PredictServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Predict",
+			Handler:    _PredictService_Predict_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamReadings",
+			Handler:       _PredictService_StreamReadings_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "predict/v1/predict.proto",
+}
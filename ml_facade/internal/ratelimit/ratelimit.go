@@ -0,0 +1,238 @@
+// Package ratelimit implements token bucket rate limiters keyed by client or by machine,
+// each key being given its own bucket.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"ml_facade/config"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Decision is the outcome of a rate limiter check, carrying what the rate limit
+// headers report to the client.
+type Decision struct {
+	Allowed bool
+	// Limit is the size of the bucket, the number of tokens that can be spent at once
+	Limit int
+	// Remaining is the number of tokens left in the bucket
+	Remaining int
+	// RetryAfter is how long to wait before the tokens requested are available, when
+	// they were refused
+	RetryAfter time.Duration
+	// Reset is how long it takes for the bucket to be full again
+	Reset time.Duration
+}
+
+// bucket is the limiter of a key, along with the last time it was used.
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Store holds token buckets shared by the replicas of the facade. Take spends n tokens
+// from the bucket of the key if it holds enough of them, and returns the tokens left.
+type Store interface {
+	Take(ctx context.Context, key string, rps float64, burst, n int) (bool, float64, error)
+}
+
+// storeTimeout bounds a call to the store, the request falling back to the local
+// buckets when it elapses.
+const storeTimeout = 100 * time.Millisecond
+
+// storeRetryInterval is how long the store is left alone after it failed.
+const storeRetryInterval = 5 * time.Second
+
+// Limiter gives every key a token bucket refilled at Rps tokens per second and
+// holding at most Burst tokens. The buckets of the keys unused for IdleTimeout are
+// evicted: the key gets a full bucket the next time it is seen, which it would have
+// anyway if IdleTimeout is longer than the time it takes to refill a bucket.
+//
+// With a store, the buckets are shared by the replicas of the facade instead, the
+// keys being prefixed with the name of the limiter. While the store is unavailable,
+// every replica falls back to its local buckets, the store being tried again every
+// storeRetryInterval.
+type Limiter struct {
+	mu          sync.Mutex
+	name        string
+	limit       rate.Limit
+	burst       int
+	idleTimeout time.Duration
+	buckets     map[string]*bucket
+	lastSweep   time.Time
+	store       Store
+	storeDown   bool
+	retryStore  time.Time
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+// New creates a limiter with the given configuration, using the store when Redis is
+// set in the configuration. It returns nil when the limiter is disabled, a nil
+// limiter allowing everything.
+func New(cfg config.CfgLimiter, name string, store Store, logger *slog.Logger) *Limiter {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	l := &Limiter{
+		name:        name,
+		limit:       rate.Limit(cfg.Rps),
+		burst:       cfg.Burst,
+		idleTimeout: cfg.IdleTimeout,
+		buckets:     make(map[string]*bucket),
+		lastSweep:   time.Now(),
+		logger:      logger,
+		now:         time.Now,
+	}
+	// The buckets of a store are only refilled at a positive rate
+	if cfg.Redis && cfg.Rps > 0 {
+		l.store = store
+	}
+	return l
+}
+
+// Allow spends a token from the bucket of the key.
+func (l *Limiter) Allow(ctx context.Context, key string) Decision {
+	return l.AllowN(ctx, key, 1)
+}
+
+// AllowN spends n tokens from the bucket of the key, or none of them if the bucket
+// does not hold enough tokens.
+func (l *Limiter) AllowN(ctx context.Context, key string, n int) Decision {
+	if l == nil {
+		return Decision{Allowed: true}
+	}
+
+	if l.useStore() {
+		storeCtx, cancel := context.WithTimeout(ctx, storeTimeout)
+		defer cancel()
+
+		allowed, tokens, err := l.store.Take(storeCtx, fmt.Sprintf("%s:%s", l.name, key), float64(l.limit), l.burst, n)
+		// A request canceled in the meantime tells nothing about the store
+		if err == nil || ctx.Err() == nil {
+			l.storeResult(err)
+		}
+		if err == nil {
+			return l.decision(allowed, tokens, n)
+		}
+	}
+
+	return l.allowLocal(key, n)
+}
+
+// allowLocal spends n tokens from the local bucket of the key.
+func (l *Limiter) allowLocal(key string, n int) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	allowed := b.limiter.AllowN(now, n)
+	return l.decision(allowed, b.limiter.TokensAt(now), n)
+}
+
+// decision describes the outcome of spending n tokens, tokens being left in the bucket.
+func (l *Limiter) decision(allowed bool, tokens float64, n int) Decision {
+	decision := Decision{
+		Allowed:   allowed,
+		Limit:     l.burst,
+		Remaining: max(int(tokens), 0),
+		Reset:     l.refill(float64(l.burst) - tokens),
+	}
+	switch {
+	case allowed:
+	case n > l.burst:
+		// More tokens than the bucket can ever hold, the request will never be allowed
+		decision.RetryAfter = l.refill(float64(l.burst))
+	default:
+		decision.RetryAfter = l.refill(float64(n) - tokens)
+	}
+	return decision
+}
+
+// useStore reports whether the store should be used, that is unless it failed less
+// than storeRetryInterval ago.
+func (l *Limiter) useStore() bool {
+	if l.store == nil {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !l.storeDown || !l.now().Before(l.retryStore)
+}
+
+// storeResult records the outcome of a call to the store, logging when the limiter
+// falls back to its local buckets and when it uses the store again.
+func (l *Limiter) storeResult(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch {
+	case err != nil:
+		if !l.storeDown {
+			l.logger.Warn(fmt.Sprintf("%s rate limiter falling back to local limiting: %v", l.name, err))
+		}
+		l.storeDown = true
+		l.retryStore = l.now().Add(storeRetryInterval)
+	case l.storeDown:
+		l.logger.Info(fmt.Sprintf("%s rate limiter using the shared buckets again", l.name))
+		l.storeDown = false
+	}
+}
+
+// Len returns the number of keys with a bucket.
+func (l *Limiter) Len() int {
+	if l == nil {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// Seconds rounds a duration up to the second, as sent in the Retry-After and the
+// X-RateLimit-Reset headers.
+func Seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// refill returns how long it takes to refill the given number of tokens.
+func (l *Limiter) refill(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	if l.limit <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(tokens / float64(l.limit) * float64(time.Second))
+}
+
+// sweep evicts the buckets of the idle keys, at most once per IdleTimeout.
+func (l *Limiter) sweep(now time.Time) {
+	if l.idleTimeout <= 0 || now.Sub(l.lastSweep) < l.idleTimeout {
+		return
+	}
+
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= l.idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,189 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"ml_facade/config"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(now *time.Time) *Limiter {
+	l := New(config.CfgLimiter{Rps: 2, Burst: 4, Enabled: true, IdleTimeout: time.Minute}, "test", nil, nil)
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	// Test Case 1: Every key gets its own bucket
+	t.Run("Keyed Buckets", func(t *testing.T) {
+		// Arrange
+		now := time.Now()
+		l := newTestLimiter(&now)
+
+		// Act
+		first := l.AllowN(ctx, "a", 4)
+		limited := l.Allow(ctx, "a")
+		other := l.Allow(ctx, "b")
+
+		// Assert
+		if !first.Allowed || first.Remaining != 0 || first.Limit != 4 {
+			t.Errorf("expected the 4 tokens of key a to be spent, got %+v", first)
+		}
+		if limited.Allowed || limited.RetryAfter != 500*time.Millisecond {
+			t.Errorf("expected key a to be limited for 500ms, got %+v", limited)
+		}
+		if limited.Reset != 2*time.Second {
+			t.Errorf("expected the bucket of key a to be full in 2s, got %v", limited.Reset)
+		}
+		if !other.Allowed || other.Remaining != 3 {
+			t.Errorf("expected key b to be allowed with 3 tokens left, got %+v", other)
+		}
+	})
+
+	// Test Case 2: Refused tokens are not spent and the bucket refills over time
+	t.Run("Refill", func(t *testing.T) {
+		// Arrange
+		now := time.Now()
+		l := newTestLimiter(&now)
+		l.AllowN(ctx, "a", 3)
+
+		// Act
+		refused := l.AllowN(ctx, "a", 2)
+		now = now.Add(500 * time.Millisecond)
+		allowed := l.AllowN(ctx, "a", 2)
+
+		// Assert
+		if refused.Allowed || refused.Remaining != 1 {
+			t.Errorf("expected 2 tokens to be refused with 1 token left, got %+v", refused)
+		}
+		if !allowed.Allowed || allowed.Remaining != 0 {
+			t.Errorf("expected 2 tokens to be allowed after the refill, got %+v", allowed)
+		}
+	})
+
+	// Test Case 3: More tokens than the burst are never allowed
+	t.Run("Above Burst", func(t *testing.T) {
+		// Arrange
+		now := time.Now()
+		l := newTestLimiter(&now)
+
+		// Act
+		decision := l.AllowN(ctx, "a", 5)
+
+		// Assert
+		if decision.Allowed || decision.Remaining != 4 {
+			t.Errorf("expected 5 tokens to be refused without spending any, got %+v", decision)
+		}
+	})
+
+	// Test Case 4: The buckets of idle keys are evicted
+	t.Run("Eviction", func(t *testing.T) {
+		// Arrange
+		now := time.Now()
+		l := newTestLimiter(&now)
+		l.Allow(ctx, "idle")
+		now = now.Add(30 * time.Second)
+		l.Allow(ctx, "active")
+
+		// Act
+		now = now.Add(45 * time.Second)
+		l.Allow(ctx, "active")
+
+		// Assert
+		if l.Len() != 1 {
+			t.Errorf("expected only the bucket of the active key to be kept, got %d buckets", l.Len())
+		}
+	})
+
+	// Test Case 5: A disabled limiter allows everything
+	t.Run("Disabled", func(t *testing.T) {
+		// Arrange
+		l := New(config.CfgLimiter{Rps: 1, Burst: 1}, "test", nil, nil)
+
+		// Act
+		decision := l.AllowN(ctx, "a", 10)
+
+		// Assert
+		if l != nil || !decision.Allowed {
+			t.Errorf("expected a nil limiter allowing everything, got %+v", decision)
+		}
+	})
+}
+
+// fakeStore records the keys it is called with and fails while err is set.
+type fakeStore struct {
+	keys []string
+	err  error
+}
+
+func (f *fakeStore) Take(_ context.Context, key string, _ float64, _, n int) (bool, float64, error) {
+	f.keys = append(f.keys, key)
+	if f.err != nil {
+		return false, 0, f.err
+	}
+	return n <= 1, 1 - float64(n), nil
+}
+
+func TestLimiterStore(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	newStoreLimiter := func(now *time.Time, store Store) *Limiter {
+		l := New(config.CfgLimiter{Rps: 2, Burst: 4, Enabled: true, Redis: true}, "client", store, logger)
+		l.now = func() time.Time { return *now }
+		return l
+	}
+
+	// Test Case 1: The buckets of the store are used when it is available
+	t.Run("Shared Buckets", func(t *testing.T) {
+		// Arrange
+		now := time.Now()
+		store := &fakeStore{}
+		l := newStoreLimiter(&now, store)
+
+		// Act
+		decision := l.AllowN(ctx, "a", 2)
+
+		// Assert
+		if decision.Allowed || decision.Limit != 4 {
+			t.Errorf("expected the decision of the store, got %+v", decision)
+		}
+		if len(store.keys) != 1 || store.keys[0] != "client:a" {
+			t.Errorf("expected the store to be called with the prefixed key, got %v", store.keys)
+		}
+		if l.Len() != 0 {
+			t.Errorf("expected no local bucket, got %d", l.Len())
+		}
+	})
+
+	// Test Case 2: The local buckets are used while the store is unavailable
+	t.Run("Fallback", func(t *testing.T) {
+		// Arrange
+		now := time.Now()
+		store := &fakeStore{err: errors.New("connection refused")}
+		l := newStoreLimiter(&now, store)
+
+		// Act
+		failed := l.AllowN(ctx, "a", 2)
+		skipped := l.AllowN(ctx, "a", 2)
+		calls := len(store.keys)
+		store.err = nil
+		now = now.Add(storeRetryInterval)
+		recovered := l.AllowN(ctx, "a", 2)
+
+		// Assert
+		if !failed.Allowed || !skipped.Allowed || skipped.Remaining != 0 {
+			t.Errorf("expected the local bucket to be used, got %+v and %+v", failed, skipped)
+		}
+		if calls != 1 {
+			t.Errorf("expected the store to be left alone after it failed, got %d calls", calls)
+		}
+		if recovered.Allowed || len(store.keys) != 2 {
+			t.Errorf("expected the store to be used again after the retry interval, got %+v", recovered)
+		}
+	})
+}
@@ -0,0 +1,262 @@
+package schema
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"ml_facade/internal/validator"
+	"os"
+	"regexp"
+	"time"
+)
+
+// Types of the sensor values. Every value is sent to the ml service as a float,
+// integer sensors only make sure that the readings hold whole numbers.
+const (
+	TypeFloat   = "float"
+	TypeInteger = "integer"
+)
+
+// Fields of a reading that are not sensors. The timestamp and the reading ID are optional.
+const (
+	MachineIDField = "machine_id"
+	TimestampField = "timestamp"
+	ReadingIDField = "reading_id"
+)
+
+// MaxReadingIDLength is the maximum length, in bytes, of a client-supplied reading ID.
+const MaxReadingIDLength = 128
+
+// MaxClockSkew is how far in the future a reading timestamp is accepted.
+const MaxClockSkew = time.Minute
+
+//go:embed default.json
+var defaultSchema []byte
+
+var sensorNameRX = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Field describes a single sensor of the machines.
+type Field struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Unit string `json:"unit,omitempty"`
+}
+
+// Reading is a decoded reading. ID is empty and Timestamp is nil if the reading did
+// not carry them.
+type Reading struct {
+	MachineID int
+	ID        string
+	Timestamp *time.Time
+	Values    map[string]float64
+}
+
+// Schema lists the sensors of the machines, in the order expected by the ml service.
+type Schema struct {
+	Sensors []Field `json:"sensors"`
+	index   map[string]int
+}
+
+// Load reads the schema from a JSON file. The default schema, made of the 52 float
+// sensors of the pump dataset, is returned when the path is empty.
+func Load(path string) (*Schema, error) {
+	if path == "" {
+		return Parse(defaultSchema)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Default returns the schema of the pump dataset.
+func Default() *Schema {
+	s, err := Parse(defaultSchema)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Parse decodes and checks a JSON schema definition.
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sensor schema: %w", err)
+	}
+
+	if len(s.Sensors) == 0 {
+		return nil, errors.New("invalid sensor schema: at least one sensor must be defined")
+	}
+
+	s.index = make(map[string]int, len(s.Sensors))
+	for i, field := range s.Sensors {
+		if field.Type == "" {
+			field.Type = TypeFloat
+			s.Sensors[i] = field
+		}
+
+		switch {
+		case !sensorNameRX.MatchString(field.Name):
+			return nil, fmt.Errorf("invalid sensor schema: invalid sensor name %q", field.Name)
+		case isReserved(field.Name):
+			return nil, fmt.Errorf("invalid sensor schema: %q is a reserved name", field.Name)
+		case field.Type != TypeFloat && field.Type != TypeInteger:
+			return nil, fmt.Errorf("invalid sensor schema: unknown type %q for sensor %q", field.Type, field.Name)
+		}
+
+		if _, exists := s.index[field.Name]; exists {
+			return nil, fmt.Errorf("invalid sensor schema: duplicate sensor %q", field.Name)
+		}
+		s.index[field.Name] = i
+	}
+
+	return &s, nil
+}
+
+// Len returns the number of sensors.
+func (s *Schema) Len() int {
+	return len(s.Sensors)
+}
+
+// Names returns the sensor names, in the schema order.
+func (s *Schema) Names() []string {
+	names := make([]string, len(s.Sensors))
+	for i, field := range s.Sensors {
+		names[i] = field.Name
+	}
+	return names
+}
+
+// Has reports whether the schema defines the given sensor.
+func (s *Schema) Has(name string) bool {
+	_, ok := s.index[name]
+	return ok
+}
+
+// Decode parses a reading made of a flat JSON object holding the machine ID, an
+// optional RFC 3339 timestamp and one field per sensor. An optional reading_id may
+// be supplied to deduplicate the reading.
+//
+// An error is returned if the data is not a JSON object. Otherwise the reading is
+// validated against the schema and the field-level errors are recorded in v: the
+// machine ID and every sensor are required, values must be finite numbers matching
+// the sensor type, the timestamp must not be in the future, and fields that are not
+// part of the schema are refused.
+func (s *Schema) Decode(v *validator.Validator, data []byte) (Reading, error) {
+	var raw map[string]json.RawMessage
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return Reading{}, err
+	}
+	if raw == nil {
+		return Reading{}, errors.New("reading must be a JSON object")
+	}
+
+	fields := make(map[string]value, len(raw))
+	for name, data := range raw {
+		fields[name] = jsonValue(data)
+	}
+	return s.decode(v, fields), nil
+}
+
+// DecodeMap validates a reading decoded from a binary format into Go values, integers
+// and floats of any size, strings, times and nil, keyed by field name. The validation
+// is the same as for Decode, timestamps being either times or RFC 3339 strings.
+func (s *Schema) DecodeMap(v *validator.Validator, data map[string]any) Reading {
+	fields := make(map[string]value, len(data))
+	for name, data := range data {
+		fields[name] = nativeValue{v: data}
+	}
+	return s.decode(v, fields)
+}
+
+func (s *Schema) decode(v *validator.Validator, fields map[string]value) Reading {
+	var machineID int
+	if field, ok := fields[MachineIDField]; !ok {
+		v.AddError(MachineIDField, "must be provided")
+	} else if id, ok := field.integer(); !ok {
+		v.AddError(MachineIDField, "must be an integer")
+	} else {
+		v.Check(id >= 0, MachineIDField, "must not be negative")
+		machineID = id
+	}
+
+	var timestamp *time.Time
+	if field, ok := fields[TimestampField]; ok && !field.null() {
+		if t, ok := field.timestamp(); !ok {
+			v.AddError(TimestampField, "must be an RFC 3339 timestamp")
+		} else {
+			v.Check(t.Before(time.Now().Add(MaxClockSkew)), TimestampField, "must not be in the future")
+			timestamp = &t
+		}
+	}
+
+	var readingID string
+	if field, ok := fields[ReadingIDField]; ok && !field.null() {
+		if id, ok := field.text(); !ok || id == "" {
+			v.AddError(ReadingIDField, "must be a non-empty string")
+		} else {
+			v.Check(len(id) <= MaxReadingIDLength, ReadingIDField, fmt.Sprintf("must not be more than %d bytes long", MaxReadingIDLength))
+			readingID = id
+		}
+	}
+
+	values := make(map[string]float64, len(s.Sensors))
+	for _, sensor := range s.Sensors {
+		field, ok := fields[sensor.Name]
+		if !ok {
+			v.AddError(sensor.Name, "must be provided")
+			continue
+		}
+
+		value, message := sensor.parse(field)
+		if message != "" {
+			v.AddError(sensor.Name, message)
+			continue
+		}
+		values[sensor.Name] = value
+	}
+
+	for name := range fields {
+		if _, known := s.index[name]; !known && !isReserved(name) {
+			v.AddError(name, "unknown field")
+		}
+	}
+
+	return Reading{MachineID: machineID, ID: readingID, Timestamp: timestamp, Values: values}
+}
+
+// Vector returns the values of a reading in the schema order, as sent to the ml service.
+func (s *Schema) Vector(values map[string]float64) []float64 {
+	vector := make([]float64, len(s.Sensors))
+	for i, field := range s.Sensors {
+		vector[i] = values[field.Name]
+	}
+	return vector
+}
+
+// parse reads the value of the sensor, returning a validation message if it is invalid.
+func (f Field) parse(field value) (float64, string) {
+	value, ok := field.number()
+	if !ok {
+		return 0, "must be a number"
+	}
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, "must be a finite number"
+	}
+
+	if f.Type == TypeInteger && value != math.Trunc(value) {
+		return 0, "must be an integer"
+	}
+	return value, ""
+}
+
+func isReserved(name string) bool {
+	return name == MachineIDField || name == TimestampField || name == ReadingIDField
+}
@@ -0,0 +1,203 @@
+package schema
+
+import (
+	"math"
+	"ml_facade/internal/validator"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	// Test Case 1: The default schema holds the 52 pump sensors
+	t.Run("Default Schema", func(t *testing.T) {
+		// Act
+		s := Default()
+
+		// Assert
+		if s.Len() != 52 {
+			t.Fatalf("expected 52 sensors, got %d", s.Len())
+		}
+		names := s.Names()
+		if names[0] != "sensor_00" || names[51] != "sensor_51" {
+			t.Errorf("expected sensors sensor_00 to sensor_51, got %s to %s", names[0], names[51])
+		}
+	})
+
+	// Test Case 2: Invalid definitions are refused
+	t.Run("Invalid Schemas", func(t *testing.T) {
+		tests := map[string]string{
+			"empty":          `{"sensors": []}`,
+			"malformed":      `{"sensors": [}`,
+			"duplicate name": `{"sensors": [{"name": "temp"}, {"name": "temp"}]}`,
+			"reserved name":  `{"sensors": [{"name": "machine_id"}]}`,
+			"invalid name":   `{"sensors": [{"name": "Temp C"}]}`,
+			"unknown type":   `{"sensors": [{"name": "temp", "type": "string"}]}`,
+		}
+
+		for name, definition := range tests {
+			// Act
+			_, err := Parse([]byte(definition))
+
+			// Assert
+			if err == nil {
+				t.Errorf("%s: expected an error, got none", name)
+			}
+		}
+	})
+}
+
+func TestDecode(t *testing.T) {
+	s, err := Parse([]byte(`{"sensors": [
+		{"name": "pressure", "type": "float", "unit": "bar"},
+		{"name": "rpm", "type": "integer", "unit": "rpm"}
+	]}`))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Test Case 1: The values are returned in the schema order
+	t.Run("Valid Reading", func(t *testing.T) {
+		// Arrange
+		v := validator.New()
+
+		// Act
+		reading, err := s.Decode(v, []byte(`{"rpm": 1500, "machine_id": 7, "pressure": 2.5, "timestamp": "2024-06-01T12:00:00Z", "reading_id": "r-1"}`))
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !v.Valid() {
+			t.Fatalf("expected a valid reading, got %v", v.Errors)
+		}
+		if reading.MachineID != 7 || reading.ID != "r-1" {
+			t.Errorf("expected machine ID 7 and reading ID r-1, got %d and %s", reading.MachineID, reading.ID)
+		}
+		if reading.Timestamp == nil || !reading.Timestamp.Equal(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)) {
+			t.Errorf("expected timestamp 2024-06-01T12:00:00Z, got %v", reading.Timestamp)
+		}
+		vector := s.Vector(reading.Values)
+		if len(vector) != 2 || vector[0] != 2.5 || vector[1] != 1500 {
+			t.Errorf("expected [2.5 1500], got %v", vector)
+		}
+	})
+
+	// Test Case 2: Invalid fields are reported one by one
+	t.Run("Invalid Fields", func(t *testing.T) {
+		tests := map[string]struct {
+			reading string
+			field   string
+		}{
+			"missing machine ID":  {`{"pressure": 2.5, "rpm": 1500}`, "machine_id"},
+			"negative machine ID": {`{"machine_id": -1, "pressure": 2.5, "rpm": 1500}`, "machine_id"},
+			"missing sensor":      {`{"machine_id": 7, "pressure": 2.5}`, "rpm"},
+			"null value":          {`{"machine_id": 7, "pressure": null, "rpm": 1500}`, "pressure"},
+			"string value":        {`{"machine_id": 7, "pressure": "2.5", "rpm": 1500}`, "pressure"},
+			"non-finite value":    {`{"machine_id": 7, "pressure": 1e400, "rpm": 1500}`, "pressure"},
+			"fractional integer":  {`{"machine_id": 7, "pressure": 2.5, "rpm": 1500.5}`, "rpm"},
+			"unknown field":       {`{"machine_id": 7, "pressure": 2.5, "rpm": 1500, "status": "NORMAL"}`, "status"},
+			"invalid timestamp":   {`{"machine_id": 7, "pressure": 2.5, "rpm": 1500, "timestamp": "yesterday"}`, "timestamp"},
+			"empty reading ID":    {`{"machine_id": 7, "pressure": 2.5, "rpm": 1500, "reading_id": ""}`, "reading_id"},
+			"future timestamp":    {`{"machine_id": 7, "pressure": 2.5, "rpm": 1500, "timestamp": "2999-01-01T00:00:00Z"}`, "timestamp"},
+		}
+
+		for name, tt := range tests {
+			// Arrange
+			v := validator.New()
+
+			// Act
+			_, err := s.Decode(v, []byte(tt.reading))
+
+			// Assert
+			if err != nil {
+				t.Errorf("%s: expected no error, got %v", name, err)
+			}
+			if len(v.Errors) != 1 || v.Errors[tt.field] == "" {
+				t.Errorf("%s: expected a single error on %s, got %v", name, tt.field, v.Errors)
+			}
+		}
+	})
+
+	// Test Case 3: Readings that are not JSON objects fail to decode
+	t.Run("Malformed Readings", func(t *testing.T) {
+		for _, reading := range []string{`[1, 2]`, `null`, `{invalid json}`} {
+			// Act
+			_, err := s.Decode(validator.New(), []byte(reading))
+
+			// Assert
+			if err == nil {
+				t.Errorf("%s: expected an error, got none", reading)
+			}
+		}
+	})
+}
+
+func TestDecodeMap(t *testing.T) {
+	s, err := Parse([]byte(`{"sensors": [
+		{"name": "pressure", "type": "float"},
+		{"name": "rpm", "type": "integer"}
+	]}`))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Test Case 1: Integers and floats of any size are accepted, as decoded from binary formats
+	t.Run("Valid Reading", func(t *testing.T) {
+		// Arrange
+		v := validator.New()
+		timestamp := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+		// Act
+		reading := s.DecodeMap(v, map[string]any{
+			"machine_id": uint8(7),
+			"timestamp":  timestamp,
+			"reading_id": "r-1",
+			"pressure":   float32(2.5),
+			"rpm":        int64(1500),
+		})
+
+		// Assert
+		if !v.Valid() {
+			t.Fatalf("expected a valid reading, got %v", v.Errors)
+		}
+		if reading.MachineID != 7 || reading.ID != "r-1" {
+			t.Errorf("expected machine ID 7 and reading ID r-1, got %d and %s", reading.MachineID, reading.ID)
+		}
+		if reading.Timestamp == nil || !reading.Timestamp.Equal(timestamp) {
+			t.Errorf("expected timestamp %v, got %v", timestamp, reading.Timestamp)
+		}
+		vector := s.Vector(reading.Values)
+		if len(vector) != 2 || vector[0] != 2.5 || vector[1] != 1500 {
+			t.Errorf("expected [2.5 1500], got %v", vector)
+		}
+	})
+
+	// Test Case 2: Fields are validated as in JSON
+	t.Run("Invalid Fields", func(t *testing.T) {
+		tests := map[string]struct {
+			reading map[string]any
+			field   string
+		}{
+			"float machine ID":   {map[string]any{"machine_id": 7.0, "pressure": 2.5, "rpm": 1500}, "machine_id"},
+			"null value":         {map[string]any{"machine_id": 7, "pressure": nil, "rpm": 1500}, "pressure"},
+			"string value":       {map[string]any{"machine_id": 7, "pressure": "2.5", "rpm": 1500}, "pressure"},
+			"non-finite value":   {map[string]any{"machine_id": 7, "pressure": math.Inf(1), "rpm": 1500}, "pressure"},
+			"fractional integer": {map[string]any{"machine_id": 7, "pressure": 2.5, "rpm": 1500.5}, "rpm"},
+			"unknown field":      {map[string]any{"machine_id": 7, "pressure": 2.5, "rpm": 1500, "status": "NORMAL"}, "status"},
+			"string timestamp":   {map[string]any{"machine_id": 7, "pressure": 2.5, "rpm": 1500, "timestamp": "yesterday"}, "timestamp"},
+		}
+
+		for name, tt := range tests {
+			// Arrange
+			v := validator.New()
+
+			// Act
+			s.DecodeMap(v, tt.reading)
+
+			// Assert
+			if len(v.Errors) != 1 || v.Errors[tt.field] == "" {
+				t.Errorf("%s: expected a single error on %s, got %v", name, tt.field, v.Errors)
+			}
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// value is the value of a field of a reading, whatever the format it was decoded from.
+type value interface {
+	null() bool
+	integer() (int, bool)
+	// number returns the value of a numeric field, or false if the field is not a number
+	number() (float64, bool)
+	text() (string, bool)
+	timestamp() (time.Time, bool)
+}
+
+// jsonValue is a field of a JSON reading.
+type jsonValue json.RawMessage
+
+func (j jsonValue) null() bool {
+	return string(bytes.TrimSpace(j)) == "null"
+}
+
+func (j jsonValue) integer() (int, bool) {
+	var i int
+	err := json.Unmarshal(j, &i)
+	return i, err == nil && !j.null()
+}
+
+func (j jsonValue) number() (float64, bool) {
+	// json.Number also accepts numeric strings, which are refused
+	var number json.Number
+	err := json.Unmarshal(j, &number)
+	if err != nil || j.null() || bytes.HasPrefix(bytes.TrimSpace(j), []byte(`"`)) {
+		return 0, false
+	}
+
+	f, err := strconv.ParseFloat(number.String(), 64)
+	if err != nil {
+		// Out of range numbers are reported as not finite
+		return math.Inf(1), true
+	}
+	return f, true
+}
+
+func (j jsonValue) text() (string, bool) {
+	var s string
+	err := json.Unmarshal(j, &s)
+	return s, err == nil && !j.null()
+}
+
+func (j jsonValue) timestamp() (time.Time, bool) {
+	var t time.Time
+	err := json.Unmarshal(j, &t)
+	return t, err == nil && !j.null()
+}
+
+// nativeValue is a field of a reading decoded into Go values, as produced by the
+// binary formats: integers and floats of any size, strings, times and nil.
+type nativeValue struct {
+	v any
+}
+
+func (n nativeValue) null() bool {
+	return n.v == nil
+}
+
+func (n nativeValue) integer() (int, bool) {
+	rv := reflect.ValueOf(n.v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt {
+			return 0, false
+		}
+		return int(rv.Uint()), true
+	default:
+		return 0, false
+	}
+}
+
+func (n nativeValue) number() (float64, bool) {
+	if i, ok := n.integer(); ok {
+		return float64(i), true
+	}
+	rv := reflect.ValueOf(n.v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+func (n nativeValue) text() (string, bool) {
+	s, ok := n.v.(string)
+	return s, ok
+}
+
+func (n nativeValue) timestamp() (time.Time, bool) {
+	switch t := n.v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		return parsed, err == nil
+	default:
+		return time.Time{}, false
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"ml_facade/internal/models/postgres_models"
+	"ml_facade/internal/schema"
+	"time"
+)
+
+// Ways a reading is identified for deduplication, reported with the duplicates.
+const (
+	DedupByReadingID = "reading_id"
+	DedupByMessageID = "message_id"
+	DedupByEventTime = "event_time"
+	DedupByJob       = "job"
+)
+
+// batch holds the accepted readings of a request along with what is needed to
+// deduplicate them and to report them at their position in the request.
+type batch struct {
+	inputs []postgres_models.Sensor
+	// keys holds the deduplication key of each input, empty if it cannot be deduplicated
+	keys []string
+	// dedupBy holds how each input was identified, see dedupKey
+	dedupBy   []string
+	positions []int
+}
+
+// add appends a reading found at the given position of the request. Readings without
+// a timestamp take receivedAt as event time. messageID is the ID of the AMQP message
+// carrying the reading, if any.
+func (b *batch) add(reading schema.Reading, position int, receivedAt time.Time, messageID string) {
+	eventTime := receivedAt
+	if reading.Timestamp != nil {
+		eventTime = *reading.Timestamp
+	}
+
+	key, by := dedupKey(reading, messageID)
+	b.inputs = append(b.inputs, postgres_models.Sensor{MachineID: reading.MachineID, EventTime: eventTime, Values: reading.Values})
+	b.keys = append(b.keys, key)
+	b.dedupBy = append(b.dedupBy, by)
+	b.positions = append(b.positions, position)
+}
+
+// without returns the batch minus the inputs flagged in drop.
+func (b batch) without(drop []bool) batch {
+	var kept batch
+	for i := range b.inputs {
+		if drop[i] {
+			continue
+		}
+		kept.inputs = append(kept.inputs, b.inputs[i])
+		kept.keys = append(kept.keys, b.keys[i])
+		kept.dedupBy = append(kept.dedupBy, b.dedupBy[i])
+		kept.positions = append(kept.positions, b.positions[i])
+	}
+	return kept
+}
+
+// keyByJob identifies the inputs without a deduplication key by their position in a
+// predict job, the batch being the chunk of the job starting at offset.
+func (b *batch) keyByJob(jobID int64, offset int) {
+	for i, key := range b.keys {
+		if key == "" {
+			b.keys[i] = fmt.Sprintf("%d:job:%d:%d", b.inputs[i].MachineID, jobID, offset+b.positions[i])
+			b.dedupBy[i] = DedupByJob
+		}
+	}
+}
+
+// claimableKeys returns the non-empty deduplication keys along with the index of their input.
+func (b batch) claimableKeys() ([]string, []int) {
+	var keys []string
+	var indices []int
+	for i, key := range b.keys {
+		if key != "" {
+			keys = append(keys, key)
+			indices = append(indices, i)
+		}
+	}
+	return keys, indices
+}
+
+// dedupKey identifies a reading by, in order of preference, the reading ID supplied by
+// the client, the ID of the AMQP message carrying it, or its machine and timestamp.
+// Readings without any of them, whose event time is their reception time, are not
+// deduplicated and get an empty key, unless they belong to a predict job: see keyByJob.
+func dedupKey(reading schema.Reading, messageID string) (string, string) {
+	switch {
+	case reading.ID != "":
+		return fmt.Sprintf("%d:reading:%s", reading.MachineID, reading.ID), DedupByReadingID
+	case messageID != "":
+		return fmt.Sprintf("%d:message:%s", reading.MachineID, messageID), DedupByMessageID
+	case reading.Timestamp != nil:
+		return fmt.Sprintf("%d:time:%d", reading.MachineID, reading.Timestamp.UnixNano()), DedupByEventTime
+	default:
+		return "", ""
+	}
+}
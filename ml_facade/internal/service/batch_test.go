@@ -0,0 +1,102 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"ml_facade/internal/schema"
+)
+
+func TestDedupKey(t *testing.T) {
+	timestamp := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		reading   schema.Reading
+		messageID string
+		key       string
+		by        string
+	}{
+		"reading ID":    {schema.Reading{MachineID: 7, ID: "r-1", Timestamp: &timestamp}, "m-1", "7:reading:r-1", DedupByReadingID},
+		"message ID":    {schema.Reading{MachineID: 7, Timestamp: &timestamp}, "m-1", "7:message:m-1", DedupByMessageID},
+		"event time":    {schema.Reading{MachineID: 7, Timestamp: &timestamp}, "", "7:time:1717243200000000000", DedupByEventTime},
+		"not deduped":   {schema.Reading{MachineID: 7}, "", "", ""},
+		"other machine": {schema.Reading{MachineID: 8, ID: "r-1"}, "", "8:reading:r-1", DedupByReadingID},
+	}
+
+	for name, tt := range tests {
+		// Act
+		key, by := dedupKey(tt.reading, tt.messageID)
+
+		// Assert
+		if key != tt.key || by != tt.by {
+			t.Errorf("%s: expected key %q by %q, got %q by %q", name, tt.key, tt.by, key, by)
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	// Arrange
+	receivedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	timestamp := receivedAt.Add(-time.Minute)
+
+	var b batch
+	b.add(schema.Reading{MachineID: 1, ID: "a"}, 0, receivedAt, "")
+	b.add(schema.Reading{MachineID: 2}, 2, receivedAt, "")
+	b.add(schema.Reading{MachineID: 3, Timestamp: &timestamp}, 5, receivedAt, "")
+
+	// Test Case 1: Readings without a timestamp take the reception time
+	t.Run("Event Time", func(t *testing.T) {
+		if !b.inputs[1].EventTime.Equal(receivedAt) {
+			t.Errorf("expected event time %v, got %v", receivedAt, b.inputs[1].EventTime)
+		}
+		if !b.inputs[2].EventTime.Equal(timestamp) {
+			t.Errorf("expected event time %v, got %v", timestamp, b.inputs[2].EventTime)
+		}
+	})
+
+	// Test Case 2: Only the readings that can be deduplicated have a key
+	t.Run("Claimable Keys", func(t *testing.T) {
+		// Act
+		keys, indices := b.claimableKeys()
+
+		// Assert
+		if len(keys) != 2 || keys[0] != "1:reading:a" || indices[0] != 0 || indices[1] != 2 {
+			t.Errorf("expected the keys of inputs 0 and 2, got %v at %v", keys, indices)
+		}
+	})
+
+	// Test Case 3: Dropped inputs keep the position of the remaining ones
+	t.Run("Without", func(t *testing.T) {
+		// Act
+		kept := b.without([]bool{true, false, false})
+
+		// Assert
+		if len(kept.inputs) != 2 {
+			t.Fatalf("expected 2 inputs, got %d", len(kept.inputs))
+		}
+		if kept.inputs[0].MachineID != 2 || kept.positions[0] != 2 || kept.positions[1] != 5 {
+			t.Errorf("expected machines 2 and 3 at positions 2 and 5, got %+v", kept)
+		}
+		if kept.keys[0] != "" || kept.dedupBy[1] != DedupByEventTime {
+			t.Errorf("expected the keys to follow their input, got %v", kept.keys)
+		}
+	})
+	// Test Case 4: The readings of a job without a key are identified by their position in the job
+	t.Run("Key By Job", func(t *testing.T) {
+		// Arrange
+		var chunk batch
+		chunk.add(schema.Reading{MachineID: 1, ID: "a"}, 0, receivedAt, "")
+		chunk.add(schema.Reading{MachineID: 2}, 1, receivedAt, "")
+
+		// Act
+		chunk.keyByJob(42, 500)
+
+		// Assert
+		if chunk.keys[0] != "1:reading:a" || chunk.dedupBy[0] != DedupByReadingID {
+			t.Errorf("expected the reading ID to be kept, got %q by %q", chunk.keys[0], chunk.dedupBy[0])
+		}
+		if chunk.keys[1] != "2:job:42:501" || chunk.dedupBy[1] != DedupByJob {
+			t.Errorf("expected the position in the job, got %q by %q", chunk.keys[1], chunk.dedupBy[1])
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"fmt"
+	"ml_facade/config"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the ml service while the circuit breaker is open.
+var ErrCircuitOpen = fmt.Errorf("%w: circuit breaker is open", ErrMlServiceUnavailable)
+
+// probeWait is the delay suggested to callers while the half-open probes are in flight.
+const probeWait = 100 * time.Millisecond
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+// circuitBreaker stops the calls to the ml service after repeated failures.
+//
+// Once FailureThreshold consecutive calls failed, the breaker opens and every call
+// fails fast for OpenTimeout. It then lets HalfOpenRequests probe calls through:
+// the breaker closes if they all succeed and opens again as soon as one fails.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	config   config.CfgCircuitBreaker
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probes   int
+	passed   int
+	now      func() time.Time
+}
+
+func newCircuitBreaker(cfg config.CfgCircuitBreaker) *circuitBreaker {
+	return &circuitBreaker{config: cfg, now: time.Now}
+}
+
+// allow reports whether a call can be made. Every allowed call must be followed
+// by a call to success, failure or release.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if b.now().Sub(b.openedAt) < b.config.OpenTimeout {
+			return ErrCircuitOpen
+		}
+		b.state = stateHalfOpen
+		b.probes, b.passed = 0, 0
+		fallthrough
+	case stateHalfOpen:
+		if b.probes >= b.config.HalfOpenRequests {
+			return ErrCircuitOpen
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// success records a successful call.
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	if b.state == stateHalfOpen {
+		b.passed++
+		if b.passed >= b.config.HalfOpenRequests {
+			b.state = stateClosed
+		}
+	}
+}
+
+// failure records a failed call and opens the breaker when needed.
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == stateHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.state = stateOpen
+		b.openedAt = b.now()
+		b.failures = 0
+	}
+}
+
+// release records a call whose outcome says nothing about the ml service health,
+// such as a call canceled by the caller.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == stateHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// retryAfter returns how long to wait before a call can be allowed, or zero if calls are allowed.
+// While the probes of a half-open breaker are in flight, it returns a short delay
+// as their outcome is not known yet.
+func (b *circuitBreaker) retryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		return max(b.config.OpenTimeout-b.now().Sub(b.openedAt), 0)
+	case stateHalfOpen:
+		if b.probes >= b.config.HalfOpenRequests {
+			return probeWait
+		}
+	}
+	return 0
+}
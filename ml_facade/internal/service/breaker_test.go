@@ -0,0 +1,136 @@
+package service
+
+import (
+	"errors"
+	"ml_facade/config"
+	"testing"
+	"time"
+)
+
+func newTestBreaker(now *time.Time) *circuitBreaker {
+	b := newCircuitBreaker(config.CfgCircuitBreaker{
+		FailureThreshold: 3,
+		OpenTimeout:      10 * time.Second,
+		HalfOpenRequests: 1,
+	})
+	b.now = func() time.Time { return *now }
+	return b
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	// Test Case 1: The breaker opens after consecutive failures
+	t.Run("Opens After Failures", func(t *testing.T) {
+		// Arrange
+		now := time.Now()
+		b := newTestBreaker(&now)
+
+		// Act
+		for i := 0; i < 3; i++ {
+			if err := b.allow(); err != nil {
+				t.Fatalf("expected call %d to be allowed, got %v", i, err)
+			}
+			b.failure()
+		}
+		err := b.allow()
+
+		// Assert
+		if !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("expected ErrCircuitOpen, got %v", err)
+		}
+		if !errors.Is(err, ErrMlServiceUnavailable) {
+			t.Error("expected ErrCircuitOpen to wrap ErrMlServiceUnavailable")
+		}
+		if b.retryAfter() != 10*time.Second {
+			t.Errorf("expected retry after 10s, got %v", b.retryAfter())
+		}
+	})
+
+	// Test Case 2: A success resets the consecutive failures
+	t.Run("Success Resets Failures", func(t *testing.T) {
+		// Arrange
+		now := time.Now()
+		b := newTestBreaker(&now)
+
+		// Act
+		b.failure()
+		b.failure()
+		b.success()
+		b.failure()
+		err := b.allow()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected call to be allowed, got %v", err)
+		}
+	})
+
+	// Test Case 3: A successful probe closes the breaker
+	t.Run("Half-Open Probe Succeeds", func(t *testing.T) {
+		// Arrange
+		now := time.Now()
+		b := newTestBreaker(&now)
+		for i := 0; i < 3; i++ {
+			b.failure()
+		}
+
+		// Act
+		now = now.Add(10 * time.Second)
+		probeErr := b.allow()
+		concurrentErr := b.allow()
+		b.success()
+		afterErr := b.allow()
+
+		// Assert
+		if probeErr != nil {
+			t.Fatalf("expected the probe to be allowed, got %v", probeErr)
+		}
+		if !errors.Is(concurrentErr, ErrCircuitOpen) {
+			t.Errorf("expected a concurrent call to be rejected, got %v", concurrentErr)
+		}
+		if afterErr != nil {
+			t.Errorf("expected the breaker to be closed, got %v", afterErr)
+		}
+	})
+
+	// Test Case 4: A failed probe opens the breaker again
+	t.Run("Half-Open Probe Fails", func(t *testing.T) {
+		// Arrange
+		now := time.Now()
+		b := newTestBreaker(&now)
+		for i := 0; i < 3; i++ {
+			b.failure()
+		}
+
+		// Act
+		now = now.Add(10 * time.Second)
+		_ = b.allow()
+		b.failure()
+		err := b.allow()
+
+		// Assert
+		if !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("expected ErrCircuitOpen, got %v", err)
+		}
+	})
+
+	// Test Case 5: A released probe lets another one through
+	t.Run("Half-Open Probe Released", func(t *testing.T) {
+		// Arrange
+		now := time.Now()
+		b := newTestBreaker(&now)
+		for i := 0; i < 3; i++ {
+			b.failure()
+		}
+
+		// Act
+		now = now.Add(10 * time.Second)
+		_ = b.allow()
+		b.release()
+		err := b.allow()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected another probe to be allowed, got %v", err)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	retryablehttp "github.com/hashicorp/go-retryablehttp"
@@ -8,94 +9,195 @@ import (
 	"ml_facade/config"
 	"ml_facade/internal/models/postgres_models"
 	"ml_facade/internal/models/redis_models"
+	"ml_facade/internal/ratelimit"
+	"ml_facade/internal/schema"
 	"net/http"
 	"sync"
 	"time"
 )
 
+// ErrMlServiceUnavailable is returned while the ml service cannot be reached.
+var ErrMlServiceUnavailable = errors.New("ml service is unavailable")
+
 type cacheEntry struct {
 	value      float64
 	expiration time.Time
 }
 
 type MlService struct {
-	client         *retryablehttp.Client
-	sensorModel    *postgres_models.SensorModel
-	thresholdModel *redis_models.ThresholdModel
-	config         config.CfgMlService
-	logger         *slog.Logger
-	thresholdCache sync.Map
-	wg             *sync.WaitGroup
-	clientReady    chan struct{}
-	clientErr      chan error
+	client          *retryablehttp.Client
+	schema          *schema.Schema
+	sensorModel     *postgres_models.SensorModel
+	thresholdModel  *redis_models.ThresholdModel
+	predictionModel *redis_models.PredictionModel
+	dedupModel      *redis_models.DedupModel
+	config          config.CfgMlService
+	validation      config.CfgValidation
+	logger          *slog.Logger
+	thresholdCache  sync.Map
+	wg              *sync.WaitGroup
+	breaker         *circuitBreaker
+	machineLimiter  *ratelimit.Limiter
+	mu              sync.RWMutex
+	available       chan struct{}
+	isAvailable     bool
 }
 
+// NewMlService creates the ml service client. It does not wait for the ml service
+// to be reachable: the service starts as unavailable until Monitor connects to it.
 func NewMlService(
 	cfg config.CfgMlService,
+	validation config.CfgValidation,
 	logger *slog.Logger,
+	sensorSchema *schema.Schema,
 	sensorModel *postgres_models.SensorModel,
 	thresholdModel *redis_models.ThresholdModel,
-	wg *sync.WaitGroup) (*MlService, error) {
-	mlService := &MlService{
-		sensorModel:    sensorModel,
-		thresholdModel: thresholdModel,
-		config:         cfg,
-		logger:         logger,
-		wg:             wg,
-		clientReady:    make(chan struct{}),
-		clientErr:      make(chan error, 1),
+	predictionModel *redis_models.PredictionModel,
+	dedupModel *redis_models.DedupModel,
+	machineLimiter *ratelimit.Limiter,
+	wg *sync.WaitGroup) *MlService {
+	client := retryablehttp.NewClient()
+	client.RetryMax = 5
+	client.RetryWaitMin = 10 * time.Millisecond
+	client.RetryWaitMax = 100 * time.Millisecond
+	client.Logger = logger
+
+	return &MlService{
+		client:          client,
+		schema:          sensorSchema,
+		sensorModel:     sensorModel,
+		thresholdModel:  thresholdModel,
+		predictionModel: predictionModel,
+		dedupModel:      dedupModel,
+		config:          cfg,
+		validation:      validation,
+		logger:          logger,
+		wg:              wg,
+		breaker:         newCircuitBreaker(cfg.Breaker),
+		machineLimiter:  machineLimiter,
+		available:       make(chan struct{}),
 	}
+}
 
-	go mlService.initClientWithRetry()
+// Monitor periodically checks the health of the ml service until the context is done.
+// While the ml service is unreachable, the checks are retried with an exponential backoff.
+func (m *MlService) Monitor(ctx context.Context) {
+	initialBackoff := time.Second
+	maxBackoff := 30 * time.Second
+	backoff := initialBackoff
 
-	select {
-	case <-mlService.clientReady:
-		return mlService, nil
-	case err := <-mlService.clientErr:
-		return nil, err
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, m.config.HealthInterval)
+		err := m.Ping(checkCtx)
+		cancel()
+
+		wait := m.config.HealthInterval
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if m.reachable() {
+				m.logger.Error(fmt.Sprintf("lost connection to ml service: %v", err))
+			} else {
+				m.logger.Warn(fmt.Sprintf("ml service is not reachable, retrying in %v: %v", backoff, err))
+			}
+			m.setAvailable(false)
+
+			wait = backoff
+			backoff = min(2*backoff, maxBackoff)
+		} else {
+			if !m.reachable() {
+				m.logger.Info("connected to ml service successfully")
+			}
+			m.setAvailable(true)
+			backoff = initialBackoff
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
 	}
 }
 
-func (m *MlService) initClientWithRetry() {
-	ErrMlServiceNotRunning := errors.New("ml service is not healthy")
+// Schema returns the sensor schema the readings are parsed with.
+func (m *MlService) Schema() *schema.Schema {
+	return m.schema
+}
 
-	client := retryablehttp.NewClient()
-	client.RetryMax = 5
-	client.RetryWaitMin = 10 * time.Millisecond
-	client.RetryWaitMax = 100 * time.Millisecond
-	client.Logger = m.logger
+// Available reports whether the ml service was reachable at the last check and
+// the circuit breaker lets calls through.
+func (m *MlService) Available() bool {
+	return m.reachable() && m.breaker.retryAfter() == 0
+}
 
-	maxRetries := 3
-	initialBackoff := time.Second
+// reachable reports whether the ml service was reachable at the last check.
+func (m *MlService) reachable() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.isAvailable
+}
 
-	for i := 0; i < maxRetries; i++ {
-		resp, err := client.Get(m.config.MlServiceUri() + "/health")
-		if err == nil && resp.StatusCode == http.StatusOK {
-			// Successfully connected, set the client and signal readiness
-			m.client = client
-			close(m.clientReady)
-			m.logger.Info("connected to ml service successfully")
-			return
+// WaitAvailable blocks until the ml service is available or the context is done.
+func (m *MlService) WaitAvailable(ctx context.Context) error {
+	for {
+		m.mu.RLock()
+		available := m.available
+		m.mu.RUnlock()
+
+		select {
+		case <-available:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 
-		if err != nil {
-			m.logger.Error(fmt.Sprintf("failed to connect to ml service: %v", err.Error()))
-		} else {
-			m.logger.Error(fmt.Sprintf("ml service returned non-OK status: %v", resp.StatusCode))
+		wait := m.breaker.retryAfter()
+		if wait == 0 {
+			return nil
 		}
 
-		backoff := initialBackoff * time.Duration(1<<i)
-		m.logger.Warn(fmt.Sprintf("retrying connection to ml service after %v", backoff))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// setAvailable records the availability of the ml service. The available channel
+// is closed while the ml service is available, and replaced once it is not anymore.
+func (m *MlService) setAvailable(available bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-		time.Sleep(backoff)
+	if available == m.isAvailable {
+		return
 	}
+	m.isAvailable = available
 
-	m.logger.Error("failed to connect to ml service after maximum retries")
-	m.clientErr <- ErrMlServiceNotRunning
-	close(m.clientReady)
+	if available {
+		close(m.available)
+	} else {
+		m.available = make(chan struct{})
+	}
 }
 
-// WaitForClientReady allows other parts of the app to wait for the ML service client to be ready
-func (m *MlService) WaitForClientReady() {
-	<-m.clientReady
+// Ping checks that the ml service reports itself as healthy.
+func (m *MlService) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.config.MlServiceUri()+"/health", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := m.client.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ml service returned non-OK status: %v", resp.StatusCode)
+	}
+	return nil
 }
@@ -1,110 +1,518 @@
 package service
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"io"
+	"ml_facade/internal/codec"
+	"ml_facade/internal/logging"
+	"ml_facade/internal/metrics"
 	"ml_facade/internal/models/postgres_models"
+	"ml_facade/internal/models/redis_models"
+	"ml_facade/internal/pb/predictv1"
+	"ml_facade/internal/schema"
+	"ml_facade/internal/validator"
 	"net/http"
+	"sort"
 	"strconv"
 	"time"
 )
 
+// ErrMalformedBody is returned when a request body is not an array of readings.
+var ErrMalformedBody = errors.New("body is badly-formed")
+
+// Readings is a batch of readings parsed one by one, like AMQP deliveries: the invalid
+// readings are rejected on their own instead of failing the whole batch.
+type Readings []json.RawMessage
+
+// Encoded is a request body in one of the binary formats of the codec package, given
+// by its media type. It is validated like a JSON io.Reader body.
+type Encoded struct {
+	MediaType string
+	Body      io.Reader
+}
+
+// JobChunk is a chunk of the readings of a predict job, starting at Offset in the job,
+// validated like a JSON io.Reader body. The readings that cannot be deduplicated otherwise
+// are identified by their position in the job: a chunk scored again, because its outcome
+// could not be saved, then lists its readings as duplicates instead of counting them twice.
+type JobChunk struct {
+	JobID  int64
+	Offset int
+	Body   io.Reader
+}
+
+// persistTimeout bounds the storage of the results once the ml service answered.
+const persistTimeout = 5 * time.Second
+
+// insertAttempts is the number of times the records are inserted before giving up,
+// waiting insertRetryDelay more after every failed attempt.
+const (
+	insertAttempts   = 3
+	insertRetryDelay = 200 * time.Millisecond
+)
+
 // HandleMlServiceRequest processes the request to the ML service, performs anomaly detection,
 // and records the results in the database.
 //
 // This function takes a request body and its origin, reads and parses the request body,
 // forwards the data to an ML service, retrieves a threshold, determines if an anomaly
 // has occurred, and records the entire transaction.
-func (m *MlService) HandleMlServiceRequest(body any, origin string) (postgres_models.MlServiceResponse, int, error) {
+//
+// When the body is a batch of AMQP deliveries, Readings or Reading messages, each input
+// is parsed on its own: invalid inputs are listed in Result.Rejected and the remaining
+// ones are still processed. Rejected inputs are reported even when an error is returned.
+//
+// The call to the ml service is bound to the context and to the configured timeout.
+// Once the ml service answered, the results are persisted even if the context is
+// canceled in the meantime, since the anomaly counters are already being updated.
+//
+// Readings are validated against the sensor schema before anything is sent to the
+// ml service: see parseInputs for the errors returned for an invalid io.Reader body.
+//
+// ErrMlServiceUnavailable is returned, without calling the ml service, while it is
+// unreachable or while the circuit breaker is open.
+//
+// Readings that were already ingested within the deduplication window are listed in
+// Result.Duplicates and dropped before the ml service is called: they neither update
+// the anomaly counters nor get stored. See dedupKey for how readings are identified.
+// The readings are only remembered for the deduplication window once stored: while
+// they are processed, they are claimed for claimTimeout, and those sent again in the
+// meantime are listed as duplicates in progress. When the request fails, the readings
+// are forgotten so that they can be sent again, even though their anomaly counters may
+// already have been updated: counting a reading twice is preferred to losing it.
+//
+// When the machine rate limiter is enabled, the readings sent through the HTTP and
+// gRPC APIs are limited per machine: see limitMachines.
+func (m *MlService) HandleMlServiceRequest(ctx context.Context, body any, origin string) (Result, error) {
 	defer m.wg.Done()
 
 	// Parse the inputs based on the body type
-	inputs, err := m.parseInputs(body)
+	parsed, rejected, err := m.parseInputs(body)
 	if err != nil {
-		return postgres_models.MlServiceResponse{}, 0, err
+		return Result{}, err
+	}
+
+	result := Result{Rejected: rejected, Duplicates: []Duplicate{}}
+	if len(parsed.inputs) == 0 {
+		return result, nil
 	}
 
-	mlRequestBody, err := m.createMLRequestBody(inputs)
+	if !m.Available() {
+		return result, ErrMlServiceUnavailable
+	}
+
+	parsed, limited, err := m.limitMachines(ctx, parsed, body, origin)
 	if err != nil {
-		return postgres_models.MlServiceResponse{}, 0, err
+		return result, err
+	}
+	if len(limited) > 0 {
+		result.Rejected = append(result.Rejected, limited...)
+		sort.SliceStable(result.Rejected, func(i, j int) bool {
+			return result.Rejected[i].Index < result.Rejected[j].Index
+		})
+		if len(parsed.inputs) == 0 {
+			return result, nil
+		}
 	}
 
-	modelResponse, err := m.forwardRequestToMLService(mlRequestBody)
+	var claimed []string
+	parsed, result.Duplicates, claimed, err = m.dropDuplicates(ctx, parsed)
 	if err != nil {
-		return postgres_models.MlServiceResponse{}, 0, err
+		return result, err
+	}
+	if len(parsed.inputs) == 0 {
+		result.Predictions = []Prediction{}
+		result.Machines = summarize(nil)
+		return result, nil
+	}
+
+	modelResponse, thresholds, err := m.score(ctx, parsed)
+	if err != nil {
+		m.releaseClaims(ctx, claimed)
+		return result, err
+	}
+
+	predictions, err := m.record(ctx, parsed, modelResponse, thresholds, origin)
+	if err != nil {
+		m.releaseClaims(ctx, claimed)
+		return result, err
+	}
+
+	commitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), persistTimeout)
+	defer cancel()
+	if err := m.dedupModel.Commit(commitCtx, claimed, m.config.DedupWindow); err != nil {
+		// The readings are stored, they are only deduplicated until claimTimeout elapses
+		m.logger.ErrorContext(ctx, fmt.Sprintf("error committing deduplication keys: %v", err))
+	}
+
+	result.ModelResponse = modelResponse
+	result.AnomalyCounter = predictions[len(predictions)-1].AnomalyCounter
+	result.Predictions = predictions
+	result.Machines = summarize(predictions)
+	return result, nil
+}
+
+// releaseClaims forgets the deduplication keys of readings that were not stored, so
+// that they can be sent again.
+func (m *MlService) releaseClaims(ctx context.Context, claimed []string) {
+	releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), persistTimeout)
+	defer cancel()
+	if err := m.dedupModel.Release(releaseCtx, claimed); err != nil {
+		m.logger.ErrorContext(ctx, fmt.Sprintf("error releasing deduplication keys: %v", err))
+	}
+}
+
+// score scores the readings through the ml service and fetches the thresholds of their
+// machines, that is everything done before the anomaly counters are updated.
+func (m *MlService) score(ctx context.Context, parsed batch) (postgres_models.MlServiceResponse, map[int]float64, error) {
+	mlRequestBody, err := m.createMLRequestBody(parsed.inputs)
+	if err != nil {
+		return postgres_models.MlServiceResponse{}, nil, err
+	}
+
+	modelResponse, err := m.forwardRequestToMLService(ctx, mlRequestBody)
+	if err != nil {
+		return modelResponse, nil, err
 	}
 
 	// Check that our input length is the same as the reconstruction errors length
-	if len(modelResponse.ReconstructionErrors) != len(inputs) {
-		return postgres_models.MlServiceResponse{}, 0, fmt.Errorf("mismatch between number of inputs and reconstruction errors")
+	if len(modelResponse.ReconstructionErrors) != len(parsed.inputs) {
+		return modelResponse, nil, fmt.Errorf("mismatch between number of inputs and reconstruction errors")
+	}
+
+	thresholds := make(map[int]float64)
+	for _, input := range parsed.inputs {
+		if _, ok := thresholds[input.MachineID]; ok {
+			continue
+		}
+		threshold, err := m.fetchOrCacheThreshold(ctx, input.MachineID)
+		if err != nil {
+			return modelResponse, nil, err
+		}
+		thresholds[input.MachineID] = threshold
+	}
+
+	return modelResponse, thresholds, nil
+}
+
+// record updates the anomaly counters with the scored readings, then stores and
+// publishes them.
+func (m *MlService) record(
+	ctx context.Context,
+	parsed batch,
+	modelResponse postgres_models.MlServiceResponse,
+	thresholds map[int]float64,
+	origin string) ([]Prediction, error) {
+
+	persistCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), persistTimeout)
+	defer cancel()
+
+	predictions, err := m.processAnomalies(persistCtx, parsed.inputs, modelResponse, thresholds)
+	if err != nil {
+		return nil, err
+	}
+	for i := range predictions {
+		predictions[i].Index = parsed.positions[i]
 	}
 
-	anomalies, anomalyCounter, err := m.processAnomalies(inputs, modelResponse)
+	records, err := m.insertRecord(persistCtx, parsed.inputs, predictions, origin)
 	if err != nil {
-		return postgres_models.MlServiceResponse{}, 0, err
+		return nil, err
+	}
+
+	m.publishPredictions(persistCtx, records)
+
+	return predictions, nil
+}
+
+// dropDuplicates claims the deduplication keys of the readings and removes the readings
+// whose key was already claimed, by an earlier request or earlier in the batch. It returns
+// the remaining readings, the duplicates and the keys claimed by this call.
+//
+// Deduplication is disabled when the deduplication window is not positive.
+func (m *MlService) dropDuplicates(ctx context.Context, parsed batch) (batch, []Duplicate, []string, error) {
+	duplicates := []Duplicate{}
+	if m.config.DedupWindow <= 0 {
+		return parsed, duplicates, nil, nil
 	}
 
-	err = m.insertRecord(inputs, modelResponse, anomalies, anomalyCounter, origin)
+	keys, indices := parsed.claimableKeys()
+	statuses, err := m.dedupModel.Claim(ctx, keys, m.claimTimeout())
 	if err != nil {
-		return postgres_models.MlServiceResponse{}, 0, err
+		return parsed, duplicates, nil, err
 	}
 
-	return modelResponse, anomalyCounter, nil
+	drop := make([]bool, len(parsed.inputs))
+	var claimedKeys []string
+	for j, status := range statuses {
+		if status == redis_models.Claimed {
+			claimedKeys = append(claimedKeys, keys[j])
+			continue
+		}
+
+		i := indices[j]
+		drop[i] = true
+		duplicates = append(duplicates, Duplicate{
+			Index:      parsed.positions[i],
+			MachineID:  parsed.inputs[i].MachineID,
+			EventTime:  parsed.inputs[i].EventTime,
+			DedupBy:    parsed.dedupBy[i],
+			InProgress: status == redis_models.InProgress,
+		})
+	}
+	metrics.DuplicateReadings.Add(float64(len(duplicates)))
+
+	return parsed.without(drop), duplicates, claimedKeys, nil
 }
 
-// parseInputs handles the input parsing based on the body type
-func (m *MlService) parseInputs(body any) ([]postgres_models.Sensor, error) {
-	var inputs []postgres_models.Sensor
+// claimTimeout is how long the readings are claimed while they are processed: long
+// enough for the ml service to answer and the results to be persisted, so that the
+// claims of a replica that crashed in the meantime do not outlive it for long.
+func (m *MlService) claimTimeout() time.Duration {
+	return m.config.Timeout + 2*persistTimeout
+}
+
+// parseInputs handles the input parsing based on the body type.
+//
+// AMQP deliveries and Readings are parsed one by one and the invalid ones are returned as rejections,
+// while an invalid io.Reader body fails as a whole: ErrMalformedBody is returned if it
+// is not a JSON array of objects, and a ValidationError if a reading does not match
+// the sensor schema or if the number of readings is out of the configured bounds.
+// Encoded bodies, JobChunks and PredictRequest messages fail the same way, ErrMalformedBody
+// being returned when they cannot be decoded, while Reading messages are parsed one by one.
+func (m *MlService) parseInputs(body any) (batch, []Rejection, error) {
+	var parsed batch
+	var rejected []Rejection
+
+	receivedAt := time.Now()
 
 	switch v := body.(type) {
 	case []amqp.Delivery:
-		for _, msg := range v {
-			var input postgres_models.Sensor
-			err := json.Unmarshal(msg.Body, &input)
+		for i, msg := range v {
+			reading, rejection := m.parseDelivery(msg)
+			if rejection != nil {
+				rejection.Index = i
+				rejected = append(rejected, *rejection)
+				continue
+			}
+
+			// Readings without a timestamp are given the publishing time of the message when it is set
+			publishedAt := receivedAt
+			if !msg.Timestamp.IsZero() {
+				publishedAt = msg.Timestamp
+			}
+			parsed.add(reading, i, publishedAt, msg.MessageId)
+		}
+	case Readings:
+		for i, data := range v {
+			reading, readingErrors, err := m.parseReading(data)
+			switch {
+			case err != nil:
+				rejected = append(rejected, Rejection{Index: i, Reason: ReasonMalformedJSON, Detail: err.Error()})
+			case len(readingErrors) > 0:
+				rejected = append(rejected, Rejection{Index: i, Reason: ReasonValidationFailed, Detail: formatErrors(readingErrors)})
+			default:
+				parsed.add(reading, i, receivedAt, "")
+			}
+		}
+	case Encoded:
+		data, err := io.ReadAll(v.Body)
+		if err != nil {
+			return batch{}, nil, err
+		}
+
+		readings, err := codec.DecodeBatch(v.MediaType, data, m.schema)
+		if err != nil {
+			return batch{}, nil, fmt.Errorf("%w: %v", ErrMalformedBody, err)
+		}
+
+		parsed, err = m.parseFields(readings, receivedAt)
+		if err != nil {
+			return batch{}, nil, err
+		}
+	case *predictv1.PredictRequest:
+		readings, err := codec.RequestFields(v, m.schema)
+		if err != nil {
+			return batch{}, nil, fmt.Errorf("%w: %v", ErrMalformedBody, err)
+		}
+
+		parsed, err = m.parseFields(readings, receivedAt)
+		if err != nil {
+			return batch{}, nil, err
+		}
+	case []*predictv1.Reading:
+		for i, msg := range v {
+			fields, err := codec.ReadingFields(msg, m.schema)
 			if err != nil {
-				return nil, err
+				rejected = append(rejected, Rejection{Index: i, Reason: ReasonMalformedBody, Detail: err.Error()})
+				continue
 			}
-			inputs = append(inputs, input)
+
+			val := validator.New()
+			reading := m.schema.DecodeMap(val, fields)
+			if !val.Valid() {
+				rejected = append(rejected, Rejection{Index: i, Reason: ReasonValidationFailed, Detail: formatErrors(val.Errors)})
+				continue
+			}
+			parsed.add(reading, i, receivedAt, "")
 		}
-	case io.Reader:
-		data, err := io.ReadAll(v)
+	case JobChunk:
+		var err error
+		parsed, err = m.parseJSON(v.Body, receivedAt)
 		if err != nil {
-			return nil, err
+			return batch{}, nil, err
 		}
-		err = json.Unmarshal(data, &inputs)
+		parsed.keyByJob(v.JobID, v.Offset)
+	case io.Reader:
+		var err error
+		parsed, err = m.parseJSON(v, receivedAt)
 		if err != nil {
-			return nil, err
+			return batch{}, nil, err
 		}
 	default:
-		return nil, fmt.Errorf("unsupported body type: %T", body)
+		return batch{}, nil, fmt.Errorf("unsupported body type: %T", body)
 	}
 
-	return inputs, nil
+	return parsed, rejected, nil
 }
 
-// createMLRequestBody converts the Sensor struct into the format required by the ML service
+// parseJSON validates the readings of a JSON request body. The body fails as a whole:
+// ErrMalformedBody is returned if it is not a JSON array of objects, and a
+// ValidationError if a reading does not match the sensor schema or if the number of
+// readings is out of the configured bounds.
+func (m *MlService) parseJSON(body io.Reader, receivedAt time.Time) (batch, error) {
+	var parsed batch
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return batch{}, err
+	}
+
+	var readings []json.RawMessage
+	err = json.Unmarshal(data, &readings)
+	if err != nil {
+		return batch{}, fmt.Errorf("%w: %v", ErrMalformedBody, err)
+	}
+
+	val := m.validateBatchSize(len(readings))
+	if !val.Valid() {
+		return batch{}, &ValidationError{Errors: val.Errors}
+	}
+
+	for i, data := range readings {
+		reading, readingErrors, err := m.parseReading(data)
+		if err != nil {
+			return batch{}, fmt.Errorf("%w: reading %d: %v", ErrMalformedBody, i, err)
+		}
+		for field, message := range readingErrors {
+			val.AddError(fmt.Sprintf("[%d].%s", i, field), message)
+		}
+		parsed.add(reading, i, receivedAt, "")
+	}
+
+	if !val.Valid() {
+		return batch{}, &ValidationError{Errors: val.Errors}
+	}
+	return parsed, nil
+}
+
+// parseFields validates the readings of a request body decoded from a binary format. The
+// body fails as a whole, like a JSON io.Reader body.
+func (m *MlService) parseFields(readings []map[string]any, receivedAt time.Time) (batch, error) {
+	var parsed batch
+
+	val := m.validateBatchSize(len(readings))
+	if !val.Valid() {
+		return batch{}, &ValidationError{Errors: val.Errors}
+	}
+
+	for i, fields := range readings {
+		readingVal := validator.New()
+		reading := m.schema.DecodeMap(readingVal, fields)
+		for field, message := range readingVal.Errors {
+			val.AddError(fmt.Sprintf("[%d].%s", i, field), message)
+		}
+		parsed.add(reading, i, receivedAt, "")
+	}
+
+	if !val.Valid() {
+		return batch{}, &ValidationError{Errors: val.Errors}
+	}
+	return parsed, nil
+}
+
+// validateBatchSize checks the number of readings of a request body against the configured bounds.
+func (m *MlService) validateBatchSize(n int) *validator.Validator {
+	v := validator.New()
+	v.Check(n >= m.validation.MinBatchSize, "body", fmt.Sprintf("must contain at least %d readings", m.validation.MinBatchSize))
+	v.Check(n <= m.validation.MaxBatchSize, "body", fmt.Sprintf("must not contain more than %d readings", m.validation.MaxBatchSize))
+	return v
+}
+
+// parseDelivery parses the body of a single AMQP delivery, in the format given by its
+// content type: JSON unless it is one of the binary formats of the codec package.
+func (m *MlService) parseDelivery(msg amqp.Delivery) (schema.Reading, *Rejection) {
+	if len(msg.Body) == 0 {
+		return schema.Reading{}, &Rejection{Reason: ReasonEmptyBody, Detail: "message body is empty"}
+	}
+	if int64(len(msg.Body)) > m.validation.MaxBodyBytes {
+		detail := fmt.Sprintf("message body must not be larger than %d bytes", m.validation.MaxBodyBytes)
+		return schema.Reading{}, &Rejection{Reason: ReasonBodyTooLarge, Detail: detail}
+	}
+
+	mediaType := codec.MediaType(msg.ContentType)
+	if mediaType != codec.JSON {
+		fields, err := codec.DecodeReading(mediaType, msg.Body, m.schema)
+		if err != nil {
+			return schema.Reading{}, &Rejection{Reason: ReasonMalformedBody, Detail: err.Error()}
+		}
+
+		v := validator.New()
+		reading := m.schema.DecodeMap(v, fields)
+		if !v.Valid() {
+			return schema.Reading{}, &Rejection{Reason: ReasonValidationFailed, Detail: formatErrors(v.Errors)}
+		}
+		return reading, nil
+	}
+
+	reading, readingErrors, err := m.parseReading(msg.Body)
+	if err != nil {
+		return reading, &Rejection{Reason: ReasonMalformedJSON, Detail: err.Error()}
+	}
+	if len(readingErrors) > 0 {
+		return reading, &Rejection{Reason: ReasonValidationFailed, Detail: formatErrors(readingErrors)}
+	}
+
+	return reading, nil
+}
+
+// parseReading decodes a single reading according to the sensor schema. The field-level
+// errors of an invalid reading are returned separately from the decoding error.
+func (m *MlService) parseReading(data []byte) (schema.Reading, map[string]string, error) {
+	v := validator.New()
+	reading, err := m.schema.Decode(v, data)
+	if err != nil {
+		return schema.Reading{}, nil, err
+	}
+	if !v.Valid() {
+		return schema.Reading{}, v.Errors, nil
+	}
+	return reading, nil, nil
+}
+
+// createMLRequestBody converts the readings into the format required by the ML service,
+// the sensor values being ordered as in the schema
 func (m *MlService) createMLRequestBody(input []postgres_models.Sensor) ([]byte, error) {
 	// Initialize a 2D slice
 	inputValues := make([][]float64, len(input))
 
 	for i, sensor := range input {
-		inputValues[i] = []float64{
-			sensor.Sensor00, sensor.Sensor01, sensor.Sensor02, sensor.Sensor03, sensor.Sensor04,
-			sensor.Sensor05, sensor.Sensor06, sensor.Sensor07, sensor.Sensor08, sensor.Sensor09,
-			sensor.Sensor10, sensor.Sensor11, sensor.Sensor12, sensor.Sensor13, sensor.Sensor14,
-			sensor.Sensor15, sensor.Sensor16, sensor.Sensor17, sensor.Sensor18, sensor.Sensor19,
-			sensor.Sensor20, sensor.Sensor21, sensor.Sensor22, sensor.Sensor23, sensor.Sensor24,
-			sensor.Sensor25, sensor.Sensor26, sensor.Sensor27, sensor.Sensor28, sensor.Sensor29,
-			sensor.Sensor30, sensor.Sensor31, sensor.Sensor32, sensor.Sensor33, sensor.Sensor34,
-			sensor.Sensor35, sensor.Sensor36, sensor.Sensor37, sensor.Sensor38, sensor.Sensor39,
-			sensor.Sensor40, sensor.Sensor41, sensor.Sensor42, sensor.Sensor43, sensor.Sensor44,
-			sensor.Sensor45, sensor.Sensor46, sensor.Sensor47, sensor.Sensor48, sensor.Sensor49,
-			sensor.Sensor50, sensor.Sensor51,
-		}
+		inputValues[i] = m.schema.Vector(sensor.Values)
 	}
 
 	mlRequest := map[string]interface{}{
@@ -117,65 +525,168 @@ func (m *MlService) createMLRequestBody(input []postgres_models.Sensor) ([]byte,
 // forwardRequestToMLService forwards the given request body to the ML service,
 // expecting a JSON response containing the model response. It returns the decoded
 // model response and any errors encountered during the request or response handling.
-func (m *MlService) forwardRequestToMLService(body []byte) (postgres_models.MlServiceResponse, error) {
-	var postError = errors.New("error making POST request to model service")
+//
+// The call goes through the circuit breaker and is canceled once the configured
+// timeout elapses, retries included. It carries the ID of the request, or of the
+// consumer batch, in an X-Request-ID header.
+func (m *MlService) forwardRequestToMLService(ctx context.Context, body []byte) (postgres_models.MlServiceResponse, error) {
+	var postError = errors.New("model service returned an error")
 	var encodingError = errors.New("error decoding response body from model service")
+	var timeoutError = fmt.Errorf("%w: request timed out", ErrMlServiceUnavailable)
 	var modelResponse postgres_models.MlServiceResponse
 
-	reqBody := bytes.NewReader(body)
-	resp, err := m.client.Post(m.config.MlServiceUri()+"/predict", "application/json", reqBody)
+	err := m.breaker.allow()
 	if err != nil {
-		m.logger.Error(err.Error())
-		return modelResponse, postError
+		metrics.MlServiceErrors.WithLabelValues("circuit_open").Inc()
+		return modelResponse, err
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MlServiceRequestDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	reqCtx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	req, err := retryablehttp.NewRequestWithContext(reqCtx, http.MethodPost, m.config.MlServiceUri()+"/predict", body)
+	if err != nil {
+		m.breaker.release()
+		return modelResponse, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if id := requestID(ctx); id != "" {
+		req.Header.Set("X-Request-ID", id)
 	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		switch {
+		case ctx.Err() != nil:
+			// The caller gave up, which says nothing about the ml service
+			m.breaker.release()
+			return modelResponse, ctx.Err()
+		case reqCtx.Err() != nil:
+			m.logger.ErrorContext(ctx, fmt.Sprintf("ml service did not answer within %v", m.config.Timeout))
+			metrics.MlServiceErrors.WithLabelValues("timeout").Inc()
+			m.breaker.failure()
+			return modelResponse, timeoutError
+		default:
+			// The ml service could not be reached, wait for the monitor to reconnect to it
+			m.logger.ErrorContext(ctx, err.Error())
+			metrics.MlServiceErrors.WithLabelValues("request").Inc()
+			m.breaker.failure()
+			m.setAvailable(false)
+			return modelResponse, ErrMlServiceUnavailable
+		}
+	}
+	defer resp.Body.Close()
+
 	if resp.StatusCode != http.StatusOK {
 		err := errors.New(resp.Status)
-		m.logger.Error(err.Error())
+		m.logger.ErrorContext(ctx, err.Error())
+		metrics.MlServiceErrors.WithLabelValues("status").Inc()
+		// Client errors come from the request, the ml service itself is healthy
+		if resp.StatusCode >= http.StatusInternalServerError {
+			m.breaker.failure()
+		} else {
+			m.breaker.success()
+		}
 		return modelResponse, postError
 	}
-	defer resp.Body.Close()
+	m.breaker.success()
 
 	err = json.NewDecoder(resp.Body).Decode(&modelResponse)
 	if err != nil {
-		m.logger.Error(err.Error())
+		m.logger.ErrorContext(ctx, err.Error())
+		metrics.MlServiceErrors.WithLabelValues("decoding").Inc()
 		return modelResponse, encodingError
 	}
 
 	return modelResponse, nil
 }
 
-// processAnomalies determines anomalies and counts them
+// requestID returns the ID sent to the ml service in the X-Request-ID header: the ID
+// of the HTTP request the readings came with, or the ID of their consumer batch.
+func requestID(ctx context.Context) string {
+	if id := logging.RequestID(ctx); id != "" {
+		return id
+	}
+	return logging.BatchID(ctx)
+}
+
+// processAnomalies determines the anomalies against the thresholds of the machines and
+// updates the anomaly counter of each reading's machine. It returns one prediction per reading, without its record ID.
+//
+// The readings are processed in event time order, so the counter of a machine follows
+// the order the readings were taken in rather than the order they arrived in. Readings
+// older than the latest reading of their machine by more than the lateness tolerance
+// are flagged as late: they are scored but leave the anomaly counter untouched.
 func (m *MlService) processAnomalies(
+	ctx context.Context,
 	inputs []postgres_models.Sensor,
 	modelResponse postgres_models.MlServiceResponse,
-) ([]bool, int, error) {
-	var anomalies []bool
-	var anomalyCounter int
+	thresholds map[int]float64,
+) ([]Prediction, error) {
+	predictions := make([]Prediction, len(inputs))
 
-	for i, input := range inputs {
-		threshold, err := m.fetchOrCacheThreshold(input.MachineID)
+	order := make([]int, len(inputs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return inputs[order[a]].EventTime.Before(inputs[order[b]].EventTime)
+	})
+
+	for _, i := range order {
+		input := inputs[i]
+
+		threshold := thresholds[input.MachineID]
+
+		late, err := m.isLate(ctx, input)
 		if err != nil {
-			return nil, 0, err
+			return nil, err
 		}
 
 		reconstructionError := modelResponse.ReconstructionErrors[i]
-		anomaly, counter, err := m.determineAnomaly(input.MachineID, reconstructionError, threshold)
+		anomaly, counter, err := m.determineAnomaly(ctx, input.MachineID, reconstructionError, threshold, late)
 		if err != nil {
-			return nil, 0, err
+			return nil, err
+		}
+
+		predictions[i] = Prediction{
+			MachineID:           input.MachineID,
+			EventTime:           input.EventTime,
+			ReconstructionError: reconstructionError,
+			Threshold:           threshold,
+			Anomaly:             anomaly,
+			AnomalyCounter:      counter,
+			Late:                late,
 		}
+	}
+
+	return predictions, nil
+}
 
-		// We only take the last counter
-		anomalyCounter = counter
-		anomalies = append(anomalies, anomaly)
+// isLate advances the watermark of the reading's machine and reports whether the
+// reading is older than the watermark by more than the lateness tolerance.
+func (m *MlService) isLate(ctx context.Context, input postgres_models.Sensor) (bool, error) {
+	watermark, err := m.thresholdModel.AdvanceWatermark(ctx, input.MachineID, input.EventTime)
+	if err != nil {
+		return false, err
 	}
 
-	return anomalies, anomalyCounter, nil
+	late := !watermark.IsZero() && input.EventTime.Before(watermark.Add(-m.config.LatenessTolerance))
+	if late {
+		metrics.LateReadings.Inc()
+	}
+	return late, nil
 }
 
 // fetchOrCacheThreshold retrieves the threshold for the given machineID. It first
 // checks the cache. If a valid cached entry is found, it returns the cached value.
 // Otherwise, it fetches the threshold from the database, caches it, and returns it.
-func (m *MlService) fetchOrCacheThreshold(machineID int) (float64, error) {
+func (m *MlService) fetchOrCacheThreshold(ctx context.Context, machineID int) (float64, error) {
 	cacheKey := fmt.Sprintf("threshold_%s", strconv.Itoa(machineID))
 	entry, found := m.thresholdCache.Load(cacheKey)
 
@@ -187,7 +698,7 @@ func (m *MlService) fetchOrCacheThreshold(machineID int) (float64, error) {
 		m.thresholdCache.Delete(cacheKey)
 	}
 
-	threshold, err := m.thresholdModel.Get(machineID)
+	threshold, err := m.thresholdModel.Get(ctx, machineID)
 	if err != nil {
 		return 0, err
 	}
@@ -196,25 +707,86 @@ func (m *MlService) fetchOrCacheThreshold(machineID int) (float64, error) {
 	return threshold, nil
 }
 
+// InvalidateThreshold drops the cached threshold of the given machineID, so that the
+// next reading of the machine uses the value currently stored in the database. The
+// other replicas drop it through WatchThresholds.
+func (m *MlService) InvalidateThreshold(machineID int) {
+	m.thresholdCache.Delete(fmt.Sprintf("threshold_%s", strconv.Itoa(machineID)))
+}
+
+// WatchThresholds drops the cached thresholds set or deleted through any replica until
+// the context is done. The whole cache is dropped every time the subscription starts,
+// since the invalidations published while it was down are missed, and the subscription
+// is retried with an exponential backoff.
+func (m *MlService) WatchThresholds(ctx context.Context) {
+	initialBackoff := time.Second
+	maxBackoff := 30 * time.Second
+	backoff := initialBackoff
+
+	for {
+		machineIDs, err := m.thresholdModel.SubscribeInvalidations(ctx)
+		if err == nil {
+			m.thresholdCache.Range(func(key, _ any) bool {
+				m.thresholdCache.Delete(key)
+				return true
+			})
+			backoff = initialBackoff
+
+			for machineID := range machineIDs {
+				m.InvalidateThreshold(machineID)
+			}
+			err = errors.New("subscription ended")
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		m.logger.Warn(fmt.Sprintf("not watching the threshold updates, retrying in %v: %v", backoff, err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
 // determineAnomaly calculates if a given reconstruction error is an anomaly based on the provided threshold.
-// It increments or decrements the anomaly counter for the given machineID accordingly.
-func (m *MlService) determineAnomaly(machineID int, reconstructionError, threshold float64) (bool, int, error) {
+// It increments or decrements the anomaly counter for the given machineID accordingly, unless the
+// reading is late, in which case the current counter is returned unchanged.
+func (m *MlService) determineAnomaly(ctx context.Context, machineID int, reconstructionError, threshold float64, late bool) (bool, int, error) {
 	var anomaly bool
 	var anomalyCounter int
 	var err error
 
+	// Only the machines with a threshold get here, which bounds the machine labels
+	metrics.MachineReconstructionError.WithLabelValues(strconv.Itoa(machineID)).Observe(reconstructionError)
+
+	if late {
+		anomalyCounter, err = m.thresholdModel.Counter(ctx, machineID)
+		if err != nil {
+			m.logger.ErrorContext(ctx, err.Error())
+			return false, 0, err
+		}
+		return reconstructionError > threshold, anomalyCounter, nil
+	}
+
+	start := time.Now()
 	if reconstructionError > threshold {
 		anomaly = true
-		anomalyCounter, err = m.thresholdModel.Increment(machineID)
+		anomalyCounter, err = m.thresholdModel.Increment(ctx, machineID)
+		metrics.RedisCounterDuration.WithLabelValues("increment").Observe(time.Since(start).Seconds())
 		if err != nil {
-			m.logger.Error(err.Error())
+			m.logger.ErrorContext(ctx, err.Error())
 			return false, 0, err
 		}
+		metrics.MachineAnomalies.WithLabelValues(strconv.Itoa(machineID)).Inc()
 	} else {
 		anomaly = false
-		anomalyCounter, err = m.thresholdModel.Decrement(machineID)
+		anomalyCounter, err = m.thresholdModel.Decrement(ctx, machineID)
+		metrics.RedisCounterDuration.WithLabelValues("decrement").Observe(time.Since(start).Seconds())
 		if err != nil {
-			m.logger.Error(err.Error())
+			m.logger.ErrorContext(ctx, err.Error())
 			return false, 0, err
 		}
 	}
@@ -222,16 +794,18 @@ func (m *MlService) determineAnomaly(machineID int, reconstructionError, thresho
 	return anomaly, anomalyCounter, nil
 }
 
-// insertRecord inserts a new record into the database, containing sensor data, model response, anomaly flag, and anomaly counter.
+// insertRecord inserts a new record per reading into the database, containing sensor data, reconstruction error,
+// anomaly flag, and the anomaly counter of its machine. It returns the stored records along with their generated ID,
+// which is also set on the predictions. The insert is attempted up to insertAttempts times, all of the records
+// being inserted in a single batch.
 func (m *MlService) insertRecord(
+	ctx context.Context,
 	inputs []postgres_models.Sensor,
-	modelResponse postgres_models.MlServiceResponse,
-	anomalies []bool,
-	anomalyCounter int,
-	origin string) error {
+	predictions []Prediction,
+	origin string) ([]postgres_models.Record, error) {
 
-	if len(inputs) != len(anomalies) {
-		return errors.New("mismatch between number of inputs and anomalies")
+	if len(inputs) != len(predictions) {
+		return nil, errors.New("mismatch between number of inputs and predictions")
 	}
 
 	// Prepare the records for bulk insert
@@ -239,17 +813,65 @@ func (m *MlService) insertRecord(
 	for i, input := range inputs {
 		records[i] = postgres_models.Record{
 			SensorData:          input,
-			ReconstructionError: modelResponse.ReconstructionErrors[i],
-			Anomaly:             anomalies[i],
-			AnomalyCounter:      anomalyCounter,
+			ReconstructionError: predictions[i].ReconstructionError,
+			Anomaly:             predictions[i].Anomaly,
+			AnomalyCounter:      predictions[i].AnomalyCounter,
+			Late:                predictions[i].Late,
 			Origin:              origin,
 		}
 	}
 
-	err := m.sensorModel.Insert(records)
-	if err != nil {
-		return err
+	// The anomaly counters are already updated, so the insert is retried on its own
+	// rather than failing the readings
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		err := m.sensorModel.Insert(ctx, records)
+		metrics.PostgresInsertDuration.Observe(time.Since(start).Seconds())
+		if err == nil {
+			break
+		}
+		metrics.PostgresInsertFailures.Inc()
+
+		if attempt == insertAttempts {
+			m.logger.ErrorContext(ctx, fmt.Sprintf("error inserting records, giving up after %d attempts: %v", attempt, err))
+			return nil, err
+		}
+		m.logger.WarnContext(ctx, fmt.Sprintf("error inserting records, attempt %d/%d: %v", attempt, insertAttempts, err))
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(time.Duration(attempt) * insertRetryDelay):
+		}
 	}
 
-	return nil
+	for i := range predictions {
+		predictions[i].ID = records[i].ID
+	}
+
+	return records, nil
+}
+
+// publishPredictions broadcasts the stored records to the live streams of their machine.
+// Publishing is best effort: the records are already persisted, so errors are only logged.
+func (m *MlService) publishPredictions(ctx context.Context, records []postgres_models.Record) {
+	events := make([]redis_models.PredictionEvent, len(records))
+	for i, record := range records {
+		events[i] = redis_models.PredictionEvent{
+			ID:                  record.ID,
+			CreatedAt:           record.CreatedAt,
+			EventTime:           record.SensorData.EventTime,
+			Late:                record.Late,
+			MachineID:           record.SensorData.MachineID,
+			ReconstructionError: record.ReconstructionError,
+			Anomaly:             record.Anomaly,
+			AnomalyCounter:      record.AnomalyCounter,
+			Origin:              record.Origin,
+		}
+	}
+
+	err := m.predictionModel.Publish(ctx, events)
+	if err != nil {
+		m.logger.ErrorContext(ctx, fmt.Sprintf("error publishing predictions: %v", err))
+	}
 }
@@ -7,34 +7,52 @@ import (
 	"testing"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"ml_facade/config"
+	"ml_facade/internal/codec"
 	"ml_facade/internal/models/postgres_models"
+	"ml_facade/internal/pb/predictv1"
+	"ml_facade/internal/schema"
 )
 
 func TestParseInputs(t *testing.T) {
-	// Create an instance of MlService
-	m := MlService{}
+	// Create an instance of MlService reading a single sensor
+	sensorSchema, err := schema.Parse([]byte(`{"sensors": [{"name": "temperature"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := MlService{
+		schema:     sensorSchema,
+		validation: config.CfgValidation{MaxBodyBytes: 1024, MinBatchSize: 1, MaxBatchSize: 3},
+	}
+	reading := func(machineID int) postgres_models.Sensor {
+		return postgres_models.Sensor{MachineID: machineID, Values: map[string]float64{"temperature": 21.5}}
+	}
 
 	// Test Case 1: Valid AMQP Delivery
 	t.Run("Valid AMQP Delivery", func(t *testing.T) {
 		// Arrange
-		sensor := postgres_models.Sensor{MachineID: 123}
-		sensorBytes, _ := json.Marshal(sensor)
+		sensorBytes, _ := json.Marshal(reading(123))
 		msgs := []amqp.Delivery{
 			{Body: sensorBytes},
 		}
 
 		// Act
-		inputs, err := m.parseInputs(msgs)
+		parsed, rejected, err := m.parseInputs(msgs)
 
 		// Assert
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
-		if len(inputs) != 1 {
-			t.Fatalf("expected 1 input, got %d", len(inputs))
+		if len(parsed.inputs) != 1 {
+			t.Fatalf("expected 1 input, got %d", len(parsed.inputs))
 		}
-		if inputs[0].MachineID != 123 {
-			t.Errorf("expected MachineID to be '123', got '%d'", inputs[0].MachineID)
+		if parsed.inputs[0].MachineID != 123 || parsed.inputs[0].Values["temperature"] != 21.5 {
+			t.Errorf("expected MachineID to be '123', got '%d'", parsed.inputs[0].MachineID)
+		}
+		if len(rejected) != 0 {
+			t.Errorf("expected no rejection, got %v", rejected)
 		}
 	})
 
@@ -46,66 +64,193 @@ func TestParseInputs(t *testing.T) {
 		}
 
 		// Act
-		inputs, err := m.parseInputs(msgs)
+		parsed, rejected, err := m.parseInputs(msgs)
 
 		// Assert
-		if err == nil {
-			t.Fatal("expected an error, got none")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if parsed.inputs != nil {
+			t.Fatalf("expected no inputs, got %v", parsed.inputs)
 		}
-		if inputs != nil {
-			t.Fatalf("expected no inputs, got %v", inputs)
+		if len(rejected) != 1 {
+			t.Fatalf("expected 1 rejection, got %d", len(rejected))
+		}
+		if rejected[0].Reason != ReasonMalformedJSON {
+			t.Errorf("expected reason '%s', got '%s'", ReasonMalformedJSON, rejected[0].Reason)
 		}
 	})
 
-	// Test Case 3: Valid io.Reader
-	t.Run("Valid io.Reader", func(t *testing.T) {
+	// Test Case 3: Mixed AMQP Deliveries, only the invalid ones are rejected
+	t.Run("Mixed AMQP Deliveries", func(t *testing.T) {
 		// Arrange
-		sensors := []postgres_models.Sensor{
-			{MachineID: 123},
-			{MachineID: 456},
+		sensorBytes, _ := json.Marshal(reading(123))
+		msgs := []amqp.Delivery{
+			{Body: sensorBytes},
+			{Body: []byte(`{invalid json}`)},
+			{Body: nil},
+			{Body: sensorBytes},
+			{Body: []byte(`{"machine_id": 123, "temperature": "hot"}`)},
+			{Body: bytes.Repeat([]byte(" "), 2048)},
+		}
+
+		// Act
+		parsed, rejected, err := m.parseInputs(msgs)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(parsed.inputs) != 2 {
+			t.Fatalf("expected 2 inputs, got %d", len(parsed.inputs))
+		}
+		if len(rejected) != 4 {
+			t.Fatalf("expected 4 rejections, got %d", len(rejected))
+		}
+		if rejected[0].Index != 1 || rejected[0].Reason != ReasonMalformedJSON {
+			t.Errorf("expected malformed JSON rejection at index 1, got %+v", rejected[0])
+		}
+		if rejected[1].Index != 2 || rejected[1].Reason != ReasonEmptyBody {
+			t.Errorf("expected empty body rejection at index 2, got %+v", rejected[1])
+		}
+		if rejected[2].Index != 4 || rejected[2].Reason != ReasonValidationFailed {
+			t.Errorf("expected validation rejection at index 4, got %+v", rejected[2])
 		}
+		if rejected[3].Index != 5 || rejected[3].Reason != ReasonBodyTooLarge {
+			t.Errorf("expected body too large rejection at index 5, got %+v", rejected[3])
+		}
+	})
+
+	// Test Case 4: Valid io.Reader
+	t.Run("Valid io.Reader", func(t *testing.T) {
+		// Arrange
+		sensors := []postgres_models.Sensor{reading(123), reading(456)}
 		sensorBytes, _ := json.Marshal(sensors)
 		reader := bytes.NewReader(sensorBytes)
 
 		// Act
-		inputs, err := m.parseInputs(reader)
+		parsed, _, err := m.parseInputs(reader)
 
 		// Assert
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
-		if len(inputs) != 2 {
-			t.Fatalf("expected 2 inputs, got %d", len(inputs))
+		if len(parsed.inputs) != 2 {
+			t.Fatalf("expected 2 inputs, got %d", len(parsed.inputs))
 		}
-		if inputs[0].MachineID != 123 {
-			t.Errorf("expected MachineID to be 123, got '%d'", inputs[0].MachineID)
+		if parsed.inputs[0].MachineID != 123 {
+			t.Errorf("expected MachineID to be 123, got '%d'", parsed.inputs[0].MachineID)
 		}
-		if inputs[1].MachineID != 456 {
-			t.Errorf("expected MachineID to be 456, got '%d'", inputs[1].MachineID)
+		if parsed.inputs[1].MachineID != 456 {
+			t.Errorf("expected MachineID to be 456, got '%d'", parsed.inputs[1].MachineID)
 		}
 	})
 
-	// Test Case 4: Invalid io.Reader (malformed JSON)
+	// Test Case 5: Invalid io.Reader (malformed JSON)
 	t.Run("Invalid io.Reader", func(t *testing.T) {
 		// Arrange
 		reader := bytes.NewReader([]byte(`{invalid json}`))
 
 		// Act
-		inputs, err := m.parseInputs(reader)
+		parsed, _, err := m.parseInputs(reader)
 
 		// Assert
-		if err == nil {
-			t.Fatal("expected an error, got none")
+		if !errors.Is(err, ErrMalformedBody) {
+			t.Fatalf("expected ErrMalformedBody, got %v", err)
 		}
-		if inputs != nil {
-			t.Fatalf("expected no inputs, got %v", inputs)
+		if parsed.inputs != nil {
+			t.Fatalf("expected no inputs, got %v", parsed.inputs)
 		}
 	})
 
-	// Test Case 5: Unsupported body type
+	// Test Case 6: Invalid io.Reader (readings not matching the schema or the batch limits)
+	t.Run("Invalid io.Reader Readings", func(t *testing.T) {
+		tests := map[string]struct {
+			body  string
+			field string
+		}{
+			"empty batch":     {`[]`, "body"},
+			"too many":        {`[{}, {}, {}, {}]`, "body"},
+			"missing sensor":  {`[{"machine_id": 1, "temperature": 20}, {"machine_id": 2}]`, "[1].temperature"},
+			"unknown field":   {`[{"machine_id": 1, "temperature": 20, "humidity": 0.4}]`, "[0].humidity"},
+			"invalid machine": {`[{"machine_id": "seven", "temperature": 20}]`, "[0].machine_id"},
+		}
+
+		for name, tt := range tests {
+			// Act
+			parsed, _, err := m.parseInputs(bytes.NewReader([]byte(tt.body)))
+
+			// Assert
+			var validationError *ValidationError
+			if !errors.As(err, &validationError) {
+				t.Errorf("%s: expected a validation error, got %v", name, err)
+				continue
+			}
+			if validationError.Errors[tt.field] == "" {
+				t.Errorf("%s: expected an error on %s, got %v", name, tt.field, validationError.Errors)
+			}
+			if parsed.inputs != nil {
+				t.Errorf("%s: expected no inputs, got %v", name, parsed.inputs)
+			}
+		}
+	})
+
+	// Test Case 7: AMQP Deliveries are deduplicated on their message ID
+	t.Run("AMQP Message ID", func(t *testing.T) {
+		// Arrange
+		sensorBytes, _ := json.Marshal(reading(123))
+		msgs := []amqp.Delivery{
+			{Body: []byte(`{invalid json}`), MessageId: "m-0"},
+			{Body: sensorBytes, MessageId: "m-1"},
+			{Body: sensorBytes},
+		}
+
+		// Act
+		parsed, _, err := m.parseInputs(msgs)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(parsed.inputs) != 2 {
+			t.Fatalf("expected 2 inputs, got %d", len(parsed.inputs))
+		}
+		if parsed.keys[0] != "123:message:m-1" || parsed.positions[0] != 1 {
+			t.Errorf("expected the message ID key at position 1, got %q at %d", parsed.keys[0], parsed.positions[0])
+		}
+		if parsed.keys[1] != "" {
+			t.Errorf("expected no key without message ID nor timestamp, got %q", parsed.keys[1])
+		}
+	})
+
+	// Test Case 8: Readings are parsed one by one, only the invalid ones are rejected
+	t.Run("Readings", func(t *testing.T) {
+		// Arrange
+		readings := Readings{
+			[]byte(`{"machine_id": 123, "temperature": 20}`),
+			[]byte(`{"machine_id": 123, "temperature": "hot"}`),
+			[]byte(`[]`),
+		}
+
+		// Act
+		parsed, rejected, err := m.parseInputs(readings)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(parsed.inputs) != 1 {
+			t.Fatalf("expected 1 input, got %d", len(parsed.inputs))
+		}
+		if len(rejected) != 2 || rejected[0].Index != 1 || rejected[0].Reason != ReasonValidationFailed || rejected[1].Reason != ReasonMalformedJSON {
+			t.Errorf("expected readings 1 and 2 to be rejected, got %+v", rejected)
+		}
+	})
+
+	// Test Case 9: Unsupported body type
 	t.Run("Unsupported Body Type", func(t *testing.T) {
 		// Act
-		inputs, err := m.parseInputs(123) // Unsupported type
+		parsed, _, err := m.parseInputs(123) // Unsupported type
 
 		// Assert
 		if err == nil {
@@ -115,8 +260,61 @@ func TestParseInputs(t *testing.T) {
 		if err.Error() != expectedError.Error() {
 			t.Errorf("expected error '%v', got '%v'", expectedError, err)
 		}
-		if inputs != nil {
-			t.Fatalf("expected no inputs, got %v", inputs)
+		if parsed.inputs != nil {
+			t.Fatalf("expected no inputs, got %v", parsed.inputs)
+		}
+	})
+
+	// Test Case 10: Encoded bodies are validated like JSON bodies
+	t.Run("Encoded", func(t *testing.T) {
+		// Arrange
+		valid, _ := proto.Marshal(&predictv1.PredictRequest{Readings: []*predictv1.Reading{
+			{MachineId: 123, Sensors: map[string]float64{"temperature": 21.5}},
+		}})
+		invalid, _ := msgpack.Marshal([]map[string]any{{"machine_id": 123, "temperature": "hot"}})
+
+		// Act
+		parsed, _, err := m.parseInputs(Encoded{MediaType: codec.Protobuf, Body: bytes.NewReader(valid)})
+		_, _, invalidErr := m.parseInputs(Encoded{MediaType: codec.MsgPack, Body: bytes.NewReader(invalid)})
+		_, _, malformedErr := m.parseInputs(Encoded{MediaType: codec.MsgPack, Body: bytes.NewReader([]byte{0xc1})})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(parsed.inputs) != 1 || parsed.inputs[0].Values["temperature"] != 21.5 {
+			t.Errorf("expected a single reading at 21.5, got %v", parsed.inputs)
+		}
+		var validationError *ValidationError
+		if !errors.As(invalidErr, &validationError) || validationError.Errors["[0].temperature"] == "" {
+			t.Errorf("expected a validation error on [0].temperature, got %v", invalidErr)
+		}
+		if !errors.Is(malformedErr, ErrMalformedBody) {
+			t.Errorf("expected ErrMalformedBody, got %v", malformedErr)
+		}
+	})
+
+	// Test Case 11: AMQP deliveries are decoded according to their content type
+	t.Run("Encoded AMQP Deliveries", func(t *testing.T) {
+		// Arrange
+		valid, _ := msgpack.Marshal(map[string]any{"machine_id": 123, "temperature": 21.5})
+		msgs := []amqp.Delivery{
+			{ContentType: codec.MsgPack, Body: valid},
+			{ContentType: codec.Protobuf, Body: []byte{0xff, 0xff}},
+		}
+
+		// Act
+		parsed, rejected, err := m.parseInputs(msgs)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(parsed.inputs) != 1 || parsed.inputs[0].MachineID != 123 {
+			t.Errorf("expected the reading of machine 123, got %v", parsed.inputs)
+		}
+		if len(rejected) != 1 || rejected[0].Index != 1 || rejected[0].Reason != ReasonMalformedBody {
+			t.Errorf("expected message 1 to be rejected as malformed, got %+v", rejected)
 		}
 	})
 }
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"ml_facade/internal/metrics"
+	"ml_facade/internal/pb/predictv1"
+	"ml_facade/internal/ratelimit"
+	"sort"
+	"strings"
+	"time"
+)
+
+// machineLimitedOrigins are the origins whose readings are subject to the rate limit of
+// their machine. The readings of the RabbitMQ queue, of the predict jobs and of the CSV
+// imports are paced by their workers instead: refusing them would lose them.
+var machineLimitedOrigins = map[string]bool{"api": true, "grpc": true}
+
+// RateLimitError is returned when a request body holds readings of machines that
+// exceeded their rate limit. The body is refused as a whole and can be sent again
+// after RetryAfter.
+type RateLimitError struct {
+	MachineIDs []int
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	machines := make([]string, len(e.MachineIDs))
+	for i, machineID := range e.MachineIDs {
+		machines[i] = fmt.Sprint(machineID)
+	}
+	return fmt.Sprintf("rate limit exceeded for machines %s", strings.Join(machines, ", "))
+}
+
+// RetryAfterSeconds returns RetryAfter rounded up to the second, as sent in a
+// Retry-After header.
+func (e *RateLimitError) RetryAfterSeconds() int {
+	return ratelimit.Seconds(e.RetryAfter)
+}
+
+// limitMachines spends a token from the bucket of the machine of every reading.
+//
+// The readings of the bodies parsed one by one are rejected on their own when their
+// machine has no token left, while the other bodies are refused as a whole with a
+// RateLimitError. The readings of the machines that were not limited still count
+// against their limit in the latter case.
+func (m *MlService) limitMachines(ctx context.Context, parsed batch, body any, origin string) (batch, []Rejection, error) {
+	if m.machineLimiter == nil || !machineLimitedOrigins[origin] {
+		return parsed, nil, nil
+	}
+
+	if !parsedOneByOne(body) {
+		counts := make(map[int]int)
+		for _, input := range parsed.inputs {
+			counts[input.MachineID]++
+		}
+
+		var limited RateLimitError
+		for machineID, count := range counts {
+			decision := m.machineLimiter.AllowN(ctx, fmt.Sprint(machineID), count)
+			if !decision.Allowed {
+				limited.MachineIDs = append(limited.MachineIDs, machineID)
+				limited.RetryAfter = max(limited.RetryAfter, decision.RetryAfter)
+			}
+		}
+		if len(limited.MachineIDs) > 0 {
+			sort.Ints(limited.MachineIDs)
+			metrics.RateLimited.WithLabelValues("machine").Add(float64(len(parsed.inputs)))
+			return batch{}, nil, &limited
+		}
+		return parsed, nil, nil
+	}
+
+	var rejected []Rejection
+	drop := make([]bool, len(parsed.inputs))
+	for i, input := range parsed.inputs {
+		decision := m.machineLimiter.Allow(ctx, fmt.Sprint(input.MachineID))
+		if !decision.Allowed {
+			drop[i] = true
+			detail := fmt.Sprintf("rate limit exceeded for machine %d, retry in %s", input.MachineID, decision.RetryAfter.Round(time.Millisecond))
+			rejected = append(rejected, Rejection{Index: parsed.positions[i], Reason: ReasonRateLimited, Detail: detail})
+		}
+	}
+	if len(rejected) == 0 {
+		return parsed, nil, nil
+	}
+
+	metrics.RateLimited.WithLabelValues("machine").Add(float64(len(rejected)))
+	return parsed.without(drop), rejected, nil
+}
+
+// parsedOneByOne reports whether the inputs of a body are parsed one by one, an
+// invalid input being rejected on its own. See parseInputs.
+func parsedOneByOne(body any) bool {
+	switch body.(type) {
+	case []amqp.Delivery, Readings, []*predictv1.Reading:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"ml_facade/config"
+	"ml_facade/internal/ratelimit"
+	"ml_facade/internal/schema"
+	"strings"
+	"testing"
+)
+
+func TestLimitMachines(t *testing.T) {
+	sensorSchema, err := schema.Parse([]byte(`{"sensors": [{"name": "temperature"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newService := func() *MlService {
+		return &MlService{
+			schema:         sensorSchema,
+			validation:     config.CfgValidation{MaxBodyBytes: 1024, MinBatchSize: 1, MaxBatchSize: 10},
+			machineLimiter: ratelimit.New(config.CfgLimiter{Rps: 1, Burst: 2, Enabled: true}, "machine", nil, nil),
+		}
+	}
+	body := `[{"machine_id": 1, "temperature": 1}, {"machine_id": 2, "temperature": 2}, {"machine_id": 1, "temperature": 3}, {"machine_id": 1, "temperature": 4}]`
+
+	// Test Case 1: Readings parsed one by one are rejected on their own
+	t.Run("Readings", func(t *testing.T) {
+		// Arrange
+		m := newService()
+		var readings Readings
+		_ = json.Unmarshal([]byte(body), &readings)
+		parsed, _, err := m.parseInputs(readings)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Act
+		kept, rejected, err := m.limitMachines(context.Background(), parsed, readings, "api")
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(kept.inputs) != 3 || kept.positions[2] != 2 {
+			t.Errorf("expected the first 3 readings to be kept, got positions %v", kept.positions)
+		}
+		if len(rejected) != 1 || rejected[0].Index != 3 || rejected[0].Reason != ReasonRateLimited {
+			t.Errorf("expected reading 3 to be rejected as rate limited, got %+v", rejected)
+		}
+	})
+
+	// Test Case 2: Other bodies are refused as a whole
+	t.Run("Whole Body", func(t *testing.T) {
+		// Arrange
+		m := newService()
+		parsed, _, err := m.parseInputs(strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Act
+		_, _, err = m.limitMachines(context.Background(), parsed, strings.NewReader(body), "api")
+
+		// Assert
+		var rateLimitError *RateLimitError
+		if !errors.As(err, &rateLimitError) {
+			t.Fatalf("expected a RateLimitError, got %v", err)
+		}
+		if len(rateLimitError.MachineIDs) != 1 || rateLimitError.MachineIDs[0] != 1 {
+			t.Errorf("expected machine 1 to be limited, got %v", rateLimitError.MachineIDs)
+		}
+		if rateLimitError.RetryAfterSeconds() <= 0 {
+			t.Errorf("expected a retry delay, got %v", rateLimitError.RetryAfter)
+		}
+	})
+
+	// Test Case 3: The readings of the RabbitMQ queue are not limited
+	t.Run("Unlimited Origin", func(t *testing.T) {
+		// Arrange
+		m := newService()
+		parsed, _, err := m.parseInputs(strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Act
+		kept, rejected, err := m.limitMachines(context.Background(), parsed, strings.NewReader(body), "rabbitmq")
+
+		// Assert
+		if err != nil || len(rejected) != 0 || len(kept.inputs) != 4 {
+			t.Errorf("expected every reading to be kept, got %d readings, %v and %v", len(kept.inputs), rejected, err)
+		}
+	})
+}
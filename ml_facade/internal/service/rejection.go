@@ -0,0 +1,55 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Reasons for which a single input can be rejected before reaching the ml service.
+const (
+	ReasonEmptyBody        = "empty_body"
+	ReasonBodyTooLarge     = "body_too_large"
+	ReasonMalformedJSON    = "malformed_json"
+	ReasonMalformedBody    = "malformed_body"
+	ReasonValidationFailed = "validation_failed"
+	ReasonRateLimited      = "rate_limited"
+)
+
+// Rejection describes an input that was discarded on its own, without failing
+// the rest of the batch it belongs to.
+type Rejection struct {
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+	Detail string `json:"detail"`
+}
+
+func (r Rejection) Error() string {
+	return fmt.Sprintf("input %d rejected (%s): %s", r.Index, r.Reason, r.Detail)
+}
+
+// ValidationError is returned when a request body does not match the sensor schema
+// or the batch limits. Errors maps every invalid field to the reason it was refused,
+// fields of a reading being prefixed by the reading index, as in "[2].sensor_07".
+type ValidationError struct {
+	Errors map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid readings: %s", formatErrors(e.Errors))
+}
+
+// formatErrors joins the field-level errors into a single message, ordered by field.
+func formatErrors(errors map[string]string) string {
+	fields := make([]string, 0, len(errors))
+	for field := range errors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = fmt.Sprintf("%s %s", field, errors[field])
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"ml_facade/internal/models/postgres_models"
+	"ml_facade/internal/pb/predictv1"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Result holds the outcome of a call to HandleMlServiceRequest.
+//
+// ModelResponse and Predictions only cover the accepted inputs, in the order they
+// were received. AnomalyCounter is the counter after the last reading of the batch,
+// whatever its machine: Machines holds the counter of every machine of the batch.
+// Rejected lists the inputs that were discarded along with their index in the batch,
+// and Duplicates the inputs that were already ingested.
+type Result struct {
+	ModelResponse  postgres_models.MlServiceResponse
+	AnomalyCounter int
+	Predictions    []Prediction
+	Machines       []MachineSummary
+	Rejected       []Rejection
+	Duplicates     []Duplicate
+}
+
+// Prediction is the outcome of a single reading, Index being its position in the batch.
+// Late readings did not update the anomaly counter, which holds the counter of the
+// machine when they were processed.
+type Prediction struct {
+	ID                  int64     `json:"id"`
+	Index               int       `json:"index"`
+	MachineID           int       `json:"machine_id"`
+	EventTime           time.Time `json:"event_time"`
+	ReconstructionError float64   `json:"reconstruction_error"`
+	Threshold           float64   `json:"threshold"`
+	Anomaly             bool      `json:"anomaly"`
+	AnomalyCounter      int       `json:"anomaly_counter"`
+	Late                bool      `json:"late"`
+}
+
+// Duplicate is a reading of the batch that was already ingested. DedupBy tells how the
+// reading was identified: by its reading ID, its message ID, its event time or its position
+// in a predict job. InProgress tells that the reading is still being processed by another
+// request, which may fail: the reading should then be sent again later.
+type Duplicate struct {
+	Index      int       `json:"index"`
+	MachineID  int       `json:"machine_id"`
+	EventTime  time.Time `json:"event_time"`
+	DedupBy    string    `json:"dedup_by"`
+	InProgress bool      `json:"in_progress"`
+}
+
+// MachineSummary aggregates the predictions of a batch for a single machine.
+// AnomalyCounter is the counter of the machine after its most recent reading of the batch.
+type MachineSummary struct {
+	MachineID              int     `json:"machine_id"`
+	Readings               int     `json:"readings"`
+	Anomalies              int     `json:"anomalies"`
+	LateReadings           int     `json:"late_readings"`
+	MaxReconstructionError float64 `json:"max_reconstruction_error"`
+	AnomalyCounter         int     `json:"anomaly_counter"`
+}
+
+// PredictResponse returns the Protobuf message of the result, as sent to the clients of
+// the predict route and of the gRPC server.
+func (r Result) PredictResponse() *predictv1.PredictResponse {
+	response := &predictv1.PredictResponse{
+		Predictions:          make([]*predictv1.Prediction, len(r.Predictions)),
+		Machines:             make([]*predictv1.MachineSummary, len(r.Machines)),
+		Duplicates:           make([]*predictv1.Duplicate, len(r.Duplicates)),
+		ReconstructionErrors: r.ModelResponse.ReconstructionErrors,
+		AnomalyCounter:       int64(r.AnomalyCounter),
+	}
+
+	for i, prediction := range r.Predictions {
+		response.Predictions[i] = &predictv1.Prediction{
+			Id:                  prediction.ID,
+			Index:               int64(prediction.Index),
+			MachineId:           int64(prediction.MachineID),
+			EventTime:           timestamppb.New(prediction.EventTime),
+			ReconstructionError: prediction.ReconstructionError,
+			Threshold:           prediction.Threshold,
+			Anomaly:             prediction.Anomaly,
+			AnomalyCounter:      int64(prediction.AnomalyCounter),
+			Late:                prediction.Late,
+		}
+	}
+	for i, machine := range r.Machines {
+		response.Machines[i] = &predictv1.MachineSummary{
+			MachineId:              int64(machine.MachineID),
+			Readings:               int64(machine.Readings),
+			Anomalies:              int64(machine.Anomalies),
+			LateReadings:           int64(machine.LateReadings),
+			MaxReconstructionError: machine.MaxReconstructionError,
+			AnomalyCounter:         int64(machine.AnomalyCounter),
+		}
+	}
+	for i, duplicate := range r.Duplicates {
+		response.Duplicates[i] = &predictv1.Duplicate{
+			Index:     int64(duplicate.Index),
+			MachineId: int64(duplicate.MachineID),
+			EventTime: timestamppb.New(duplicate.EventTime),
+			DedupBy:   duplicate.DedupBy,
+		}
+	}
+
+	return response
+}
+
+// summarize groups the predictions by machine, in the order the machines first
+// appear in the batch.
+func summarize(predictions []Prediction) []MachineSummary {
+	summaries := []MachineSummary{}
+	index := make(map[int]int)
+	// Event time of the most recent reading on time of each machine
+	lastEventTimes := []time.Time{}
+
+	for _, prediction := range predictions {
+		i, ok := index[prediction.MachineID]
+		if !ok {
+			i = len(summaries)
+			index[prediction.MachineID] = i
+			summaries = append(summaries, MachineSummary{
+				MachineID:              prediction.MachineID,
+				MaxReconstructionError: prediction.ReconstructionError,
+			})
+			lastEventTimes = append(lastEventTimes, time.Time{})
+		}
+
+		summary := &summaries[i]
+		summary.Readings++
+		if prediction.Anomaly {
+			summary.Anomalies++
+		}
+		if prediction.Late {
+			summary.LateReadings++
+		}
+		summary.MaxReconstructionError = max(summary.MaxReconstructionError, prediction.ReconstructionError)
+
+		// The counter was last updated by the most recent reading on time, late readings
+		// only hold the current counter
+		switch {
+		case !prediction.Late && !prediction.EventTime.Before(lastEventTimes[i]):
+			summary.AnomalyCounter = prediction.AnomalyCounter
+			lastEventTimes[i] = prediction.EventTime
+		case lastEventTimes[i].IsZero():
+			summary.AnomalyCounter = prediction.AnomalyCounter
+		}
+	}
+
+	return summaries
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	// Test Case 1: Predictions are grouped by machine in order of appearance
+	t.Run("Several Machines", func(t *testing.T) {
+		// Arrange
+		predictions := []Prediction{
+			{MachineID: 7, ReconstructionError: 0.5, Anomaly: false, AnomalyCounter: 0},
+			{MachineID: 3, ReconstructionError: 2.0, Anomaly: true, AnomalyCounter: 4},
+			{MachineID: 7, ReconstructionError: 1.5, Anomaly: true, AnomalyCounter: 1},
+			{MachineID: 7, ReconstructionError: 1.2, Anomaly: true, AnomalyCounter: 2},
+		}
+
+		// Act
+		summaries := summarize(predictions)
+
+		// Assert
+		if len(summaries) != 2 {
+			t.Fatalf("expected 2 summaries, got %d", len(summaries))
+		}
+		expected := []MachineSummary{
+			{MachineID: 7, Readings: 3, Anomalies: 2, MaxReconstructionError: 1.5, AnomalyCounter: 2},
+			{MachineID: 3, Readings: 1, Anomalies: 1, MaxReconstructionError: 2.0, AnomalyCounter: 4},
+		}
+		for i := range expected {
+			if summaries[i] != expected[i] {
+				t.Errorf("expected summary %+v, got %+v", expected[i], summaries[i])
+			}
+		}
+	})
+
+	// Test Case 2: The counter comes from the most recent reading on time
+	t.Run("Out Of Order And Late Readings", func(t *testing.T) {
+		// Arrange
+		now := time.Now()
+		predictions := []Prediction{
+			{MachineID: 7, EventTime: now, Anomaly: true, AnomalyCounter: 3},
+			{MachineID: 7, EventTime: now.Add(-time.Second), Anomaly: true, AnomalyCounter: 2},
+			{MachineID: 7, EventTime: now.Add(-time.Hour), Anomaly: false, AnomalyCounter: 3, Late: true},
+		}
+
+		// Act
+		summaries := summarize(predictions)
+
+		// Assert
+		if len(summaries) != 1 {
+			t.Fatalf("expected 1 summary, got %d", len(summaries))
+		}
+		if summaries[0].AnomalyCounter != 3 || summaries[0].LateReadings != 1 || summaries[0].Anomalies != 2 {
+			t.Errorf("unexpected summary %+v", summaries[0])
+		}
+	})
+
+	// Test Case 3: No predictions give an empty summary
+	t.Run("No Predictions", func(t *testing.T) {
+		// Act
+		summaries := summarize(nil)
+
+		// Assert
+		if summaries == nil || len(summaries) != 0 {
+			t.Errorf("expected an empty summary, got %v", summaries)
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package validator
+
+import (
+	"slices"
+)
+
+// Validator collects field-level validation errors.
+type Validator struct {
+	Errors map[string]string
+}
+
+func New() *Validator {
+	return &Validator{Errors: make(map[string]string)}
+}
+
+// Valid returns true if no error has been recorded.
+func (v *Validator) Valid() bool {
+	return len(v.Errors) == 0
+}
+
+// AddError records an error for the given key, unless one is already recorded.
+func (v *Validator) AddError(key, message string) {
+	if _, exists := v.Errors[key]; !exists {
+		v.Errors[key] = message
+	}
+}
+
+// Check records an error for the given key if ok is false.
+func (v *Validator) Check(ok bool, key, message string) {
+	if !ok {
+		v.AddError(key, message)
+	}
+}
+
+// PermittedValue returns true if the value is one of the permitted values.
+func PermittedValue[T comparable](value T, permittedValues ...T) bool {
+	return slices.Contains(permittedValues, value)
+}
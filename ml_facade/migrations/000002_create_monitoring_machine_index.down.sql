@@ -0,0 +1 @@
+DROP INDEX IF EXISTS monitoring_machine_id_created_at_id_idx;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS monitoring_machine_id_created_at_id_idx ON monitoring (machine_id, created_at, id);
@@ -0,0 +1,110 @@
+ALTER TABLE monitoring
+    ADD COLUMN IF NOT EXISTS sensor_00 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_01 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_02 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_03 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_04 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_05 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_06 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_07 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_08 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_09 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_10 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_11 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_12 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_13 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_14 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_15 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_16 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_17 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_18 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_19 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_20 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_21 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_22 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_23 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_24 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_25 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_26 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_27 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_28 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_29 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_30 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_31 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_32 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_33 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_34 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_35 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_36 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_37 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_38 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_39 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_40 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_41 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_42 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_43 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_44 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_45 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_46 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_47 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_48 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_49 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_50 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_51 DECIMAL,
+    ADD COLUMN IF NOT EXISTS sensor_52 DECIMAL;
+
+UPDATE monitoring SET
+    sensor_00 = (sensors->>'sensor_00')::DECIMAL,
+    sensor_01 = (sensors->>'sensor_01')::DECIMAL,
+    sensor_02 = (sensors->>'sensor_02')::DECIMAL,
+    sensor_03 = (sensors->>'sensor_03')::DECIMAL,
+    sensor_04 = (sensors->>'sensor_04')::DECIMAL,
+    sensor_05 = (sensors->>'sensor_05')::DECIMAL,
+    sensor_06 = (sensors->>'sensor_06')::DECIMAL,
+    sensor_07 = (sensors->>'sensor_07')::DECIMAL,
+    sensor_08 = (sensors->>'sensor_08')::DECIMAL,
+    sensor_09 = (sensors->>'sensor_09')::DECIMAL,
+    sensor_10 = (sensors->>'sensor_10')::DECIMAL,
+    sensor_11 = (sensors->>'sensor_11')::DECIMAL,
+    sensor_12 = (sensors->>'sensor_12')::DECIMAL,
+    sensor_13 = (sensors->>'sensor_13')::DECIMAL,
+    sensor_14 = (sensors->>'sensor_14')::DECIMAL,
+    sensor_15 = (sensors->>'sensor_15')::DECIMAL,
+    sensor_16 = (sensors->>'sensor_16')::DECIMAL,
+    sensor_17 = (sensors->>'sensor_17')::DECIMAL,
+    sensor_18 = (sensors->>'sensor_18')::DECIMAL,
+    sensor_19 = (sensors->>'sensor_19')::DECIMAL,
+    sensor_20 = (sensors->>'sensor_20')::DECIMAL,
+    sensor_21 = (sensors->>'sensor_21')::DECIMAL,
+    sensor_22 = (sensors->>'sensor_22')::DECIMAL,
+    sensor_23 = (sensors->>'sensor_23')::DECIMAL,
+    sensor_24 = (sensors->>'sensor_24')::DECIMAL,
+    sensor_25 = (sensors->>'sensor_25')::DECIMAL,
+    sensor_26 = (sensors->>'sensor_26')::DECIMAL,
+    sensor_27 = (sensors->>'sensor_27')::DECIMAL,
+    sensor_28 = (sensors->>'sensor_28')::DECIMAL,
+    sensor_29 = (sensors->>'sensor_29')::DECIMAL,
+    sensor_30 = (sensors->>'sensor_30')::DECIMAL,
+    sensor_31 = (sensors->>'sensor_31')::DECIMAL,
+    sensor_32 = (sensors->>'sensor_32')::DECIMAL,
+    sensor_33 = (sensors->>'sensor_33')::DECIMAL,
+    sensor_34 = (sensors->>'sensor_34')::DECIMAL,
+    sensor_35 = (sensors->>'sensor_35')::DECIMAL,
+    sensor_36 = (sensors->>'sensor_36')::DECIMAL,
+    sensor_37 = (sensors->>'sensor_37')::DECIMAL,
+    sensor_38 = (sensors->>'sensor_38')::DECIMAL,
+    sensor_39 = (sensors->>'sensor_39')::DECIMAL,
+    sensor_40 = (sensors->>'sensor_40')::DECIMAL,
+    sensor_41 = (sensors->>'sensor_41')::DECIMAL,
+    sensor_42 = (sensors->>'sensor_42')::DECIMAL,
+    sensor_43 = (sensors->>'sensor_43')::DECIMAL,
+    sensor_44 = (sensors->>'sensor_44')::DECIMAL,
+    sensor_45 = (sensors->>'sensor_45')::DECIMAL,
+    sensor_46 = (sensors->>'sensor_46')::DECIMAL,
+    sensor_47 = (sensors->>'sensor_47')::DECIMAL,
+    sensor_48 = (sensors->>'sensor_48')::DECIMAL,
+    sensor_49 = (sensors->>'sensor_49')::DECIMAL,
+    sensor_50 = (sensors->>'sensor_50')::DECIMAL,
+    sensor_51 = (sensors->>'sensor_51')::DECIMAL;
+
+ALTER TABLE monitoring DROP COLUMN IF EXISTS sensors;
@@ -0,0 +1,117 @@
+ALTER TABLE monitoring ADD COLUMN IF NOT EXISTS sensors JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+-- jsonb_build_object takes at most 100 arguments, the sensors are merged in two halves
+UPDATE monitoring SET sensors = jsonb_strip_nulls(
+    jsonb_build_object(
+        'sensor_00', sensor_00,
+        'sensor_01', sensor_01,
+        'sensor_02', sensor_02,
+        'sensor_03', sensor_03,
+        'sensor_04', sensor_04,
+        'sensor_05', sensor_05,
+        'sensor_06', sensor_06,
+        'sensor_07', sensor_07,
+        'sensor_08', sensor_08,
+        'sensor_09', sensor_09,
+        'sensor_10', sensor_10,
+        'sensor_11', sensor_11,
+        'sensor_12', sensor_12,
+        'sensor_13', sensor_13,
+        'sensor_14', sensor_14,
+        'sensor_15', sensor_15,
+        'sensor_16', sensor_16,
+        'sensor_17', sensor_17,
+        'sensor_18', sensor_18,
+        'sensor_19', sensor_19,
+        'sensor_20', sensor_20,
+        'sensor_21', sensor_21,
+        'sensor_22', sensor_22,
+        'sensor_23', sensor_23,
+        'sensor_24', sensor_24,
+        'sensor_25', sensor_25
+    )
+    ||
+    jsonb_build_object(
+        'sensor_26', sensor_26,
+        'sensor_27', sensor_27,
+        'sensor_28', sensor_28,
+        'sensor_29', sensor_29,
+        'sensor_30', sensor_30,
+        'sensor_31', sensor_31,
+        'sensor_32', sensor_32,
+        'sensor_33', sensor_33,
+        'sensor_34', sensor_34,
+        'sensor_35', sensor_35,
+        'sensor_36', sensor_36,
+        'sensor_37', sensor_37,
+        'sensor_38', sensor_38,
+        'sensor_39', sensor_39,
+        'sensor_40', sensor_40,
+        'sensor_41', sensor_41,
+        'sensor_42', sensor_42,
+        'sensor_43', sensor_43,
+        'sensor_44', sensor_44,
+        'sensor_45', sensor_45,
+        'sensor_46', sensor_46,
+        'sensor_47', sensor_47,
+        'sensor_48', sensor_48,
+        'sensor_49', sensor_49,
+        'sensor_50', sensor_50,
+        'sensor_51', sensor_51
+    )
+);
+
+ALTER TABLE monitoring
+    DROP COLUMN IF EXISTS sensor_00,
+    DROP COLUMN IF EXISTS sensor_01,
+    DROP COLUMN IF EXISTS sensor_02,
+    DROP COLUMN IF EXISTS sensor_03,
+    DROP COLUMN IF EXISTS sensor_04,
+    DROP COLUMN IF EXISTS sensor_05,
+    DROP COLUMN IF EXISTS sensor_06,
+    DROP COLUMN IF EXISTS sensor_07,
+    DROP COLUMN IF EXISTS sensor_08,
+    DROP COLUMN IF EXISTS sensor_09,
+    DROP COLUMN IF EXISTS sensor_10,
+    DROP COLUMN IF EXISTS sensor_11,
+    DROP COLUMN IF EXISTS sensor_12,
+    DROP COLUMN IF EXISTS sensor_13,
+    DROP COLUMN IF EXISTS sensor_14,
+    DROP COLUMN IF EXISTS sensor_15,
+    DROP COLUMN IF EXISTS sensor_16,
+    DROP COLUMN IF EXISTS sensor_17,
+    DROP COLUMN IF EXISTS sensor_18,
+    DROP COLUMN IF EXISTS sensor_19,
+    DROP COLUMN IF EXISTS sensor_20,
+    DROP COLUMN IF EXISTS sensor_21,
+    DROP COLUMN IF EXISTS sensor_22,
+    DROP COLUMN IF EXISTS sensor_23,
+    DROP COLUMN IF EXISTS sensor_24,
+    DROP COLUMN IF EXISTS sensor_25,
+    DROP COLUMN IF EXISTS sensor_26,
+    DROP COLUMN IF EXISTS sensor_27,
+    DROP COLUMN IF EXISTS sensor_28,
+    DROP COLUMN IF EXISTS sensor_29,
+    DROP COLUMN IF EXISTS sensor_30,
+    DROP COLUMN IF EXISTS sensor_31,
+    DROP COLUMN IF EXISTS sensor_32,
+    DROP COLUMN IF EXISTS sensor_33,
+    DROP COLUMN IF EXISTS sensor_34,
+    DROP COLUMN IF EXISTS sensor_35,
+    DROP COLUMN IF EXISTS sensor_36,
+    DROP COLUMN IF EXISTS sensor_37,
+    DROP COLUMN IF EXISTS sensor_38,
+    DROP COLUMN IF EXISTS sensor_39,
+    DROP COLUMN IF EXISTS sensor_40,
+    DROP COLUMN IF EXISTS sensor_41,
+    DROP COLUMN IF EXISTS sensor_42,
+    DROP COLUMN IF EXISTS sensor_43,
+    DROP COLUMN IF EXISTS sensor_44,
+    DROP COLUMN IF EXISTS sensor_45,
+    DROP COLUMN IF EXISTS sensor_46,
+    DROP COLUMN IF EXISTS sensor_47,
+    DROP COLUMN IF EXISTS sensor_48,
+    DROP COLUMN IF EXISTS sensor_49,
+    DROP COLUMN IF EXISTS sensor_50,
+    DROP COLUMN IF EXISTS sensor_51,
+    DROP COLUMN IF EXISTS sensor_52;
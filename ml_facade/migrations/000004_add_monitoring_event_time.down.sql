@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS monitoring_machine_id_event_time_idx;
+
+ALTER TABLE monitoring
+    DROP COLUMN IF EXISTS event_time,
+    DROP COLUMN IF EXISTS late;
@@ -0,0 +1,10 @@
+ALTER TABLE monitoring
+    ADD COLUMN IF NOT EXISTS event_time timestamp(3) with time zone,
+    ADD COLUMN IF NOT EXISTS late BOOLEAN NOT NULL DEFAULT false;
+
+-- Readings stored so far carried no timestamp, their ingestion time is the best estimate
+UPDATE monitoring SET event_time = created_at WHERE event_time IS NULL;
+
+ALTER TABLE monitoring ALTER COLUMN event_time SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS monitoring_machine_id_event_time_idx ON monitoring (machine_id, event_time);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS predict_jobs;
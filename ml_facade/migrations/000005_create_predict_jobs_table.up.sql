@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS predict_jobs (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    status TEXT NOT NULL DEFAULT 'queued',
+    readings JSONB NOT NULL,
+    total_readings INTEGER NOT NULL,
+    chunk_size INTEGER NOT NULL,
+    processed_chunks INTEGER NOT NULL DEFAULT 0,
+    predictions JSONB NOT NULL DEFAULT '[]',
+    duplicates JSONB NOT NULL DEFAULT '[]',
+    errors JSONB NOT NULL DEFAULT '[]',
+    -- A running job whose lease expired is picked up again by another worker
+    locked_until timestamp(3) with time zone
+);
+
+CREATE INDEX IF NOT EXISTS predict_jobs_status_idx ON predict_jobs (status, id);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    name TEXT NOT NULL,
+    -- Only the SHA-256 hash of the key is stored, along with its first characters
+    -- to tell the keys apart
+    hash bytea NOT NULL UNIQUE,
+    prefix TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    expires_at timestamp(0) with time zone,
+    revoked_at timestamp(0) with time zone,
+    last_used_at timestamp(0) with time zone
+);
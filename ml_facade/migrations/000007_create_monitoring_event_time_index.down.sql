@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS monitoring_machine_id_event_time_idx ON monitoring (machine_id, event_time);
+
+DROP INDEX IF EXISTS monitoring_machine_id_event_time_id_idx;
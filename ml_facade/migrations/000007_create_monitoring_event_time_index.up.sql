@@ -0,0 +1,4 @@
+-- The records are paginated on (event_time, id) by default
+CREATE INDEX IF NOT EXISTS monitoring_machine_id_event_time_id_idx ON monitoring (machine_id, event_time, id);
+
+DROP INDEX IF EXISTS monitoring_machine_id_event_time_idx;
@@ -0,0 +1,3 @@
+-- Incremented each time a job is claimed, so that a worker whose lease expired cannot
+-- update the job once another worker picked it up
+ALTER TABLE predict_jobs ADD COLUMN IF NOT EXISTS lease_token BIGINT NOT NULL DEFAULT 0;
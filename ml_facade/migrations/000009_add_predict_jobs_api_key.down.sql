@@ -0,0 +1 @@
+ALTER TABLE predict_jobs DROP COLUMN IF EXISTS api_key_id;
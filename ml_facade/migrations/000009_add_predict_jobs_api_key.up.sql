@@ -0,0 +1,3 @@
+-- The key that created a job, the only one allowed to read it along with the admin
+-- keys. It is NULL for the jobs created while authentication is disabled
+ALTER TABLE predict_jobs ADD COLUMN IF NOT EXISTS api_key_id BIGINT REFERENCES api_keys (id);
@@ -1,6 +1,7 @@
 curl -X 'POST' \
   'http://localhost:4000/v1/predict' \
   -H 'accept: application/json' \
+  -H "Authorization: Bearer ${ADMIN_API_KEY}" \
   -H 'Content-Type: application/json' \
   -d '[
   {
@@ -0,0 +1,104 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"ml_facade/internal/pb/predictv1"
+	"ml_facade/internal/schema"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// dialGrpc connects to the gRPC server of the application
+func dialGrpc(t *testing.T) *grpc.ClientConn {
+	conn, err := grpc.Dial(fmt.Sprintf("localhost:%d", testCfg.GrpcServer.Port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { closeOrLog(conn) })
+	return conn
+}
+
+// testReading returns a reading of the test machine as a Reading message
+func testReading() *predictv1.Reading {
+	return &predictv1.Reading{MachineId: machineID, Values: schema.Default().Vector(testSensor().Values)}
+}
+
+// Check if the gRPC server reports its health
+func TestGrpcHealth(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := healthpb.NewHealthClient(dialGrpc(t))
+
+	// Act
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: predictv1.PredictService_ServiceDesc.ServiceName})
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("expected status SERVING, got %s", resp.Status)
+	}
+}
+
+// Check if the Predict RPC scores a batch and refuses invalid readings
+func TestGrpcPredict(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := predictv1.NewPredictServiceClient(dialGrpc(t))
+
+	// Act
+	resp, err := client.Predict(ctx, &predictv1.PredictRequest{Readings: []*predictv1.Reading{testReading()}})
+	_, invalidErr := client.Predict(ctx, &predictv1.PredictRequest{Readings: []*predictv1.Reading{{MachineId: machineID}}})
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Predictions) != 1 || resp.Predictions[0].MachineId != machineID {
+		t.Errorf("expected a prediction for machine %d, got %v", machineID, resp.Predictions)
+	}
+	if status.Code(invalidErr) != codes.InvalidArgument {
+		t.Errorf("expected code InvalidArgument, got %v", invalidErr)
+	}
+}
+
+// Check if the StreamReadings RPC scores the stream and rejects the invalid readings on their own
+func TestGrpcStreamReadings(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := predictv1.NewPredictServiceClient(dialGrpc(t))
+	stream, err := client.StreamReadings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	for _, reading := range []*predictv1.Reading{testReading(), {MachineId: machineID}, testReading()} {
+		if err := stream.Send(reading); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Readings != 3 || resp.Accepted != 2 {
+		t.Errorf("expected 2 of 3 readings to be accepted, got %d of %d", resp.Accepted, resp.Readings)
+	}
+	if len(resp.Rejected) != 1 || resp.Rejected[0].Index != 1 {
+		t.Errorf("expected reading 1 to be rejected, got %v", resp.Rejected)
+	}
+}
@@ -1,12 +1,26 @@
 package test
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"ml_facade/internal/codec"
 	"ml_facade/internal/models/postgres_models"
+	"ml_facade/internal/models/redis_models"
+	"ml_facade/internal/pb/predictv1"
+	"ml_facade/internal/schema"
+	"ml_facade/internal/service"
 	"net/http"
+	neturl "net/url"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
 )
 
 // Check if the healthcheck route works normally
@@ -25,6 +39,37 @@ func TestHealthcheck(t *testing.T) {
 	checkStatus(t, resp.StatusCode, http.StatusOK)
 }
 
+// Check if the request IDs sent by the clients are kept, and invalid ones replaced
+func TestRequestID(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/health", testCfg.ApiServer.Port)
+	send := func(id string) string {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-Request-ID", id)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer closeOrLog(resp.Body)
+		return resp.Header.Get("X-Request-ID")
+	}
+
+	// Act
+	kept := send("gateway-42:7")
+	replaced := send("bad id\n")
+
+	// Assert
+	if kept != "gateway-42:7" {
+		t.Errorf("expected the request ID to be kept, got %q", kept)
+	}
+	if replaced == "" || replaced == "bad id\n" {
+		t.Errorf("expected the invalid request ID to be replaced, got %q", replaced)
+	}
+}
+
 // Check if the routes return a MethodNotAllowed if wrong request is sent
 func TestWrongMethod(t *testing.T) {
 	// Arrange
@@ -67,62 +112,7 @@ func TestWrongRoute(t *testing.T) {
 func TestPredictRoute(t *testing.T) {
 	// Arrange
 	url := fmt.Sprintf("http://localhost:%d/v1/predict", testCfg.ApiServer.Port)
-	sensorData := postgres_models.Sensor{
-		MachineID: machineID,
-		Sensor00:  0.1,
-		Sensor01:  0.2,
-		Sensor02:  0.3,
-		Sensor03:  0.4,
-		Sensor04:  0.5,
-		Sensor05:  0.6,
-		Sensor06:  0.7,
-		Sensor07:  0.8,
-		Sensor08:  0.9,
-		Sensor09:  1.0,
-		Sensor10:  1.1,
-		Sensor11:  1.2,
-		Sensor12:  1.3,
-		Sensor13:  1.4,
-		Sensor14:  1.5,
-		Sensor15:  1.6,
-		Sensor16:  1.7,
-		Sensor17:  1.8,
-		Sensor18:  1.9,
-		Sensor19:  2.0,
-		Sensor20:  2.1,
-		Sensor21:  2.2,
-		Sensor22:  2.3,
-		Sensor23:  2.4,
-		Sensor24:  2.5,
-		Sensor25:  2.6,
-		Sensor26:  2.7,
-		Sensor27:  2.8,
-		Sensor28:  2.9,
-		Sensor29:  3.0,
-		Sensor30:  3.1,
-		Sensor31:  3.2,
-		Sensor32:  3.3,
-		Sensor33:  3.4,
-		Sensor34:  3.5,
-		Sensor35:  3.6,
-		Sensor36:  3.7,
-		Sensor37:  3.8,
-		Sensor38:  3.9,
-		Sensor39:  4.0,
-		Sensor40:  4.1,
-		Sensor41:  4.2,
-		Sensor42:  4.3,
-		Sensor43:  4.4,
-		Sensor44:  4.5,
-		Sensor45:  4.6,
-		Sensor46:  4.7,
-		Sensor47:  4.8,
-		Sensor48:  4.9,
-		Sensor49:  5.0,
-		Sensor50:  5.1,
-		Sensor51:  5.2,
-	}
-	jsonData, err := json.Marshal([]postgres_models.Sensor{sensorData})
+	jsonData, err := json.Marshal([]postgres_models.Sensor{testSensor()})
 	if err != nil {
 		fmt.Println(err)
 		return
@@ -135,6 +125,869 @@ func TestPredictRoute(t *testing.T) {
 	}
 	defer closeOrLog(resp.Body)
 
+	var body struct {
+		Predictions []service.Prediction     `json:"predictions"`
+		Machines    []service.MachineSummary `json:"machines"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	// Assert
 	checkStatus(t, resp.StatusCode, http.StatusCreated)
+	if len(body.Predictions) != 1 {
+		t.Fatalf("expected 1 prediction, got %d", len(body.Predictions))
+	}
+	prediction := body.Predictions[0]
+	if prediction.MachineID != machineID || prediction.ID == 0 || prediction.Threshold != 1.0 {
+		t.Errorf("unexpected prediction: %+v", prediction)
+	}
+	if len(body.Machines) != 1 || body.Machines[0].Readings != 1 {
+		t.Errorf("unexpected machine summaries: %+v", body.Machines)
+	}
+}
+
+func TestPredictRouteInvalidBody(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/v1/predict", testCfg.ApiServer.Port)
+	tests := map[string]struct {
+		body   string
+		status int
+	}{
+		"malformed JSON": {`[{"machine_id": 7,`, http.StatusBadRequest},
+		"empty batch":    {`[]`, http.StatusUnprocessableEntity},
+		"missing sensor": {`[{"machine_id": 7}]`, http.StatusUnprocessableEntity},
+		"too large":      {`[` + strings.Repeat(" ", 2<<20) + `]`, http.StatusRequestEntityTooLarge},
+	}
+
+	for name, tt := range tests {
+		// Act
+		resp, err := http.Post(url, "application/json", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		closeOrLog(resp.Body)
+
+		// Assert
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d, got %d", name, tt.status, resp.StatusCode)
+		}
+	}
+}
+
+func TestPredictRouteLateReading(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/v1/predict", testCfg.ApiServer.Port)
+	onTime := testSensor()
+	onTime.EventTime = time.Now().Add(-time.Second)
+	late := testSensor()
+	late.EventTime = onTime.EventTime.Add(-time.Hour)
+
+	// Act
+	var predictions []service.Prediction
+	for _, reading := range []postgres_models.Sensor{onTime, late} {
+		jsonData, err := json.Marshal([]postgres_models.Sensor{reading})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var body struct {
+			Predictions []service.Prediction `json:"predictions"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		closeOrLog(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkStatus(t, resp.StatusCode, http.StatusCreated)
+		predictions = append(predictions, body.Predictions...)
+	}
+
+	// Assert
+	if len(predictions) != 2 {
+		t.Fatalf("expected 2 predictions, got %d", len(predictions))
+	}
+	if predictions[0].Late || !predictions[1].Late {
+		t.Errorf("expected only the second reading to be late, got %+v", predictions)
+	}
+	if !predictions[1].EventTime.Equal(late.EventTime) {
+		t.Errorf("expected event time %v, got %v", late.EventTime, predictions[1].EventTime)
+	}
+}
+
+func TestPredictRouteDuplicateReading(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/v1/predict", testCfg.ApiServer.Port)
+	reading := testSensor()
+	reading.EventTime = time.Now().Add(-2 * time.Second)
+	jsonData, err := json.Marshal([]postgres_models.Sensor{reading})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	var statuses []int
+	var body struct {
+		Predictions []service.Prediction `json:"predictions"`
+		Duplicates  []service.Duplicate  `json:"duplicates"`
+	}
+	for i := 0; i < 2; i++ {
+		resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		closeOrLog(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		statuses = append(statuses, resp.StatusCode)
+	}
+
+	// Assert
+	checkStatus(t, statuses[0], http.StatusCreated)
+	checkStatus(t, statuses[1], http.StatusOK)
+	if len(body.Predictions) != 0 {
+		t.Errorf("expected no prediction for the duplicate, got %+v", body.Predictions)
+	}
+	if len(body.Duplicates) != 1 || body.Duplicates[0].Index != 0 || body.Duplicates[0].DedupBy != service.DedupByEventTime || body.Duplicates[0].InProgress {
+		t.Errorf("expected the reading to be reported as a duplicate, got %+v", body.Duplicates)
+	}
+}
+
+// Check if a reading that could not be stored is processed again when it is redelivered
+func TestPredictRouteRedeliveryAfterInsertFailure(t *testing.T) {
+	// Arrange
+	// The machine ID fits the schema but not the integer column of the records, so the
+	// reading is scored and counted but its insert fails
+	unstorableMachineID := 3_000_000_000
+	threshold, err := json.Marshal(redis_models.Threshold{MachineID: unstorableMachineID, Threshold: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	thresholdResp, err := http.Post(fmt.Sprintf("http://localhost:%d/v1/threshold", testCfg.ApiServer.Port),
+		"application/json", bytes.NewBuffer(threshold))
+	if err != nil {
+		t.Fatal(err)
+	}
+	closeOrLog(thresholdResp.Body)
+	checkStatus(t, thresholdResp.StatusCode, http.StatusCreated)
+
+	url := fmt.Sprintf("http://localhost:%d/v1/predict", testCfg.ApiServer.Port)
+	reading := testSensor()
+	reading.MachineID = unstorableMachineID
+	reading.EventTime = time.Now().Add(-2 * time.Second)
+	jsonData, err := json.Marshal([]postgres_models.Sensor{reading})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	var statuses []int
+	for i := 0; i < 2; i++ {
+		resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		closeOrLog(resp.Body)
+		statuses = append(statuses, resp.StatusCode)
+	}
+
+	// Assert
+	checkStatus(t, statuses[0], http.StatusInternalServerError)
+	// A duplicate would be answered with 200, the reading is processed, and fails, again
+	checkStatus(t, statuses[1], http.StatusInternalServerError)
+}
+
+func TestPredictRouteIdempotencyKey(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/v1/predict", testCfg.ApiServer.Port)
+	post := func(reading postgres_models.Sensor) (*http.Response, []byte) {
+		jsonData, err := json.Marshal([]postgres_models.Sensor{reading})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "predict-route-test")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer closeOrLog(resp.Body)
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, body
+	}
+	reading := testSensor()
+	other := testSensor()
+	other.Values["sensor_00"] = 42
+
+	// Act
+	first, firstBody := post(reading)
+	replay, replayBody := post(reading)
+	mismatch, _ := post(other)
+
+	// Assert
+	checkStatus(t, first.StatusCode, http.StatusCreated)
+	checkStatus(t, replay.StatusCode, http.StatusCreated)
+	if replay.Header.Get("Idempotent-Replayed") != "true" {
+		t.Errorf("expected the response to be replayed")
+	}
+	if !bytes.Equal(firstBody, replayBody) {
+		t.Errorf("expected the original response %s, got %s", firstBody, replayBody)
+	}
+	checkStatus(t, mismatch.StatusCode, http.StatusUnprocessableEntity)
+}
+
+func TestPredictJobRoutes(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/v1/predict/jobs", testCfg.ApiServer.Port)
+	jsonData, err := json.Marshal([]postgres_models.Sensor{testSensor(), testSensor()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created struct {
+		Job postgres_models.Job `json:"job"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&created)
+	closeOrLog(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStatus(t, resp.StatusCode, http.StatusAccepted)
+
+	// Poll the job until it is done
+	var body struct {
+		Job struct {
+			Status          string               `json:"status"`
+			ProcessedChunks int                  `json:"processed_chunks"`
+			Predictions     []service.Prediction `json:"predictions"`
+		} `json:"job"`
+	}
+	for i := 0; i < 50 && body.Job.Status != postgres_models.JobCompleted; i++ {
+		time.Sleep(200 * time.Millisecond)
+
+		resp, err := http.Get(fmt.Sprintf("%s/%d", url, created.Job.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		closeOrLog(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkStatus(t, resp.StatusCode, http.StatusOK)
+	}
+
+	// Assert
+	if body.Job.Status != postgres_models.JobCompleted {
+		t.Fatalf("expected the job to complete, got status %s", body.Job.Status)
+	}
+	if body.Job.ProcessedChunks != 2 || len(body.Job.Predictions) != 2 {
+		t.Fatalf("expected 2 chunks and predictions, got %+v", body.Job)
+	}
+	if body.Job.Predictions[0].Index != 0 || body.Job.Predictions[1].Index != 1 {
+		t.Errorf("expected the predictions of readings 0 and 1, got %+v", body.Job.Predictions)
+	}
+}
+
+func TestImportCSVRoute(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/v1/import/csv?machine_id=%d", testCfg.ApiServer.Port, machineID)
+	names := schema.Default().Names()
+	values := make([]string, len(names))
+	for i := range values {
+		values[i] = "0.5"
+	}
+	file := strings.Join([]string{
+		",timestamp," + strings.Join(names, ",") + ",machine_status",
+		"0,2018-04-01 00:00:00," + strings.Join(values, ",") + ",NORMAL",
+		"1,2018-04-01 00:01:00," + strings.Join(values[1:], ",") + ",,NORMAL",
+	}, "\n")
+
+	// Act
+	resp, err := http.Post(url, "text/csv", strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeOrLog(resp.Body)
+
+	var body struct {
+		Import struct {
+			Rows     int `json:"rows"`
+			Accepted int `json:"accepted"`
+			Rejected []struct {
+				Row int `json:"row"`
+			} `json:"rejected"`
+		} `json:"import"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	checkStatus(t, resp.StatusCode, http.StatusOK)
+	if body.Import.Rows != 2 || body.Import.Accepted != 1 {
+		t.Errorf("expected 1 of 2 rows to be accepted, got %+v", body.Import)
+	}
+	if len(body.Import.Rejected) != 1 || body.Import.Rejected[0].Row != 3 {
+		t.Errorf("expected the row on line 3 to be rejected, got %+v", body.Import.Rejected)
+	}
+}
+
+// Check if the rows read before an upload error are imported and reported
+func TestImportCSVRouteUploadError(t *testing.T) {
+	// Arrange
+	mapping := neturl.QueryEscape(`{"padding": ""}`)
+	url := fmt.Sprintf("http://localhost:%d/v1/import/csv?machine_id=%d&mapping=%s", testCfg.ApiServer.Port, machineID, mapping)
+	names := schema.Default().Names()
+	values := make([]string, len(names))
+	for i := range values {
+		values[i] = "0.5"
+	}
+	// The second row goes over the body size limit
+	file := strings.Join([]string{
+		",timestamp," + strings.Join(names, ",") + ",machine_status,padding",
+		"0,2018-04-01 00:00:00," + strings.Join(values, ",") + ",NORMAL,",
+		"1,2018-04-01 00:01:00," + strings.Join(values, ",") + ",NORMAL," + strings.Repeat("x", int(testCfg.Import.MaxBodyBytes)),
+	}, "\n")
+
+	// Act
+	resp, err := http.Post(url, "text/csv", strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeOrLog(resp.Body)
+
+	var body struct {
+		Import struct {
+			Rows     int `json:"rows"`
+			Accepted int `json:"accepted"`
+		} `json:"import"`
+		Error string `json:"error"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	checkStatus(t, resp.StatusCode, http.StatusRequestEntityTooLarge)
+	if body.Import.Rows != 1 || body.Import.Accepted != 1 {
+		t.Errorf("expected the first row to be accepted, got %+v", body.Import)
+	}
+	if body.Error == "" {
+		t.Errorf("expected the upload error to be reported")
+	}
+}
+
+func TestPredictRouteNDJSON(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/v1/predict", testCfg.ApiServer.Port)
+	reading, err := json.Marshal(testSensor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(reading) + "\n\n" + `{"machine_id": 1234}` + "\n"
+
+	// Act
+	resp, err := http.Post(url, "application/x-ndjson", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeOrLog(resp.Body)
+
+	type result struct {
+		Index      int                 `json:"index"`
+		Prediction *service.Prediction `json:"prediction"`
+		Rejected   *service.Rejection  `json:"rejected"`
+	}
+	var results []result
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var result result
+		err := json.Unmarshal(scanner.Bytes(), &result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, result)
+	}
+
+	// Assert
+	checkStatus(t, resp.StatusCode, http.StatusOK)
+	if resp.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Errorf("expected an NDJSON response, got %s", resp.Header.Get("Content-Type"))
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 result lines, got %d", len(results))
+	}
+	if results[0].Index != 0 || results[0].Prediction == nil {
+		t.Errorf("expected a prediction for the first reading, got %+v", results[0])
+	}
+	if results[1].Index != 1 || results[1].Rejected == nil || results[1].Rejected.Reason != service.ReasonValidationFailed {
+		t.Errorf("expected the second reading to be rejected, got %+v", results[1])
+	}
+}
+
+// Check if an Idempotency-Key is refused on the streamed NDJSON predict requests
+func TestPredictRouteNDJSONIdempotencyKey(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/v1/predict", testCfg.ApiServer.Port)
+	reading, err := json.Marshal(testSensor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(append(reading, '\n')))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Idempotency-Key", "predict-ndjson-test")
+
+	// Act
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeOrLog(resp.Body)
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	checkStatus(t, resp.StatusCode, http.StatusBadRequest)
+	if !strings.Contains(body.Error, "Idempotency-Key") {
+		t.Errorf("expected the Idempotency-Key to be refused, got %q", body.Error)
+	}
+}
+
+// Check if the predict route reads and writes the binary formats
+func TestPredictRouteBinaryFormats(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/v1/predict", testCfg.ApiServer.Port)
+	post := func(contentType, accept string, body []byte) (*http.Response, []byte) {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept", accept)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer closeOrLog(resp.Body)
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, data
+	}
+
+	reading := testSensor()
+	protoBody, err := proto.Marshal(&predictv1.PredictRequest{Readings: []*predictv1.Reading{
+		{MachineId: machineID, Values: schema.Default().Vector(reading.Values)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgpackBody, err := msgpack.Marshal([]map[string]any{{"machine_id": machineID, "sensor_00": "invalid"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	protoResp, protoData := post(codec.Protobuf, codec.MsgPack, protoBody)
+	msgpackResp, msgpackData := post(codec.MsgPack, codec.Protobuf, msgpackBody)
+
+	// Assert
+	checkStatus(t, protoResp.StatusCode, http.StatusCreated)
+	if protoResp.Header.Get("Content-Type") != codec.MsgPack {
+		t.Errorf("expected a MessagePack response, got %s", protoResp.Header.Get("Content-Type"))
+	}
+	var response struct {
+		Predictions []service.Prediction `json:"predictions"`
+	}
+	dec := msgpack.NewDecoder(bytes.NewReader(protoData))
+	dec.SetCustomStructTag("json")
+	if err := dec.Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Predictions) != 1 || response.Predictions[0].MachineID != machineID {
+		t.Errorf("expected a prediction for machine %d, got %+v", machineID, response.Predictions)
+	}
+
+	checkStatus(t, msgpackResp.StatusCode, http.StatusUnprocessableEntity)
+	var protoError predictv1.Error
+	if err := proto.Unmarshal(msgpackData, &protoError); err != nil {
+		t.Fatal(err)
+	}
+	if protoError.Fields["[0].sensor_00"] == "" {
+		t.Errorf("expected an error on [0].sensor_00, got %v", protoError.Fields)
+	}
+}
+
+func TestShowThresholdRoute(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/v1/thresholds/%d", testCfg.ApiServer.Port, machineID)
+
+	// Act
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeOrLog(resp.Body)
+
+	var body struct {
+		Threshold redis_models.Threshold `json:"threshold"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	checkStatus(t, resp.StatusCode, http.StatusOK)
+	if body.Threshold.MachineID != machineID || body.Threshold.Threshold != 1.0 {
+		t.Errorf("unexpected threshold: %+v", body.Threshold)
+	}
+}
+
+func TestListThresholdsRoute(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/v1/thresholds", testCfg.ApiServer.Port)
+
+	// Act
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeOrLog(resp.Body)
+
+	var body struct {
+		Thresholds []redis_models.Threshold `json:"thresholds"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	checkStatus(t, resp.StatusCode, http.StatusOK)
+	if len(body.Thresholds) == 0 {
+		t.Error("expected at least one threshold")
+	}
+}
+
+func TestDeleteThresholdRoute(t *testing.T) {
+	// Arrange
+	deletedMachineID := machineID + 1
+	threshold, err := json.Marshal(redis_models.Threshold{MachineID: deletedMachineID, Threshold: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	createResp, err := http.Post(fmt.Sprintf("http://localhost:%d/v1/threshold", testCfg.ApiServer.Port),
+		"application/json", bytes.NewBuffer(threshold))
+	if err != nil {
+		t.Fatal(err)
+	}
+	closeOrLog(createResp.Body)
+
+	url := fmt.Sprintf("http://localhost:%d/v1/thresholds/%d", testCfg.ApiServer.Port, deletedMachineID)
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeOrLog(resp.Body)
+
+	notFoundResp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeOrLog(notFoundResp.Body)
+
+	// Assert
+	checkStatus(t, resp.StatusCode, http.StatusOK)
+	checkStatus(t, notFoundResp.StatusCode, http.StatusNotFound)
+}
+
+func TestListRecordsRoute(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/v1/machines/%d/records?fields=sensors&limit=1", testCfg.ApiServer.Port, machineID)
+
+	// Act
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeOrLog(resp.Body)
+
+	var body struct {
+		Records []struct {
+			MachineID int                `json:"machine_id"`
+			Sensors   map[string]float64 `json:"sensors"`
+		} `json:"records"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	checkStatus(t, resp.StatusCode, http.StatusOK)
+	if len(body.Records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(body.Records))
+	}
+	if body.Records[0].MachineID != machineID || len(body.Records[0].Sensors) != schema.Default().Len()+1 {
+		t.Errorf("unexpected record: %+v", body.Records[0])
+	}
+}
+
+// Check if the records are filtered on the time their reading was taken at by default
+func TestListRecordsRouteTimeField(t *testing.T) {
+	// Arrange
+	backfilled := testSensor()
+	backfilled.EventTime = time.Now().Add(-3 * time.Hour).Truncate(time.Millisecond)
+	jsonData, err := json.Marshal([]postgres_models.Sensor{backfilled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(fmt.Sprintf("http://localhost:%d/v1/predict", testCfg.ApiServer.Port), "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	closeOrLog(resp.Body)
+	checkStatus(t, resp.StatusCode, http.StatusCreated)
+
+	from := backfilled.EventTime.Add(-time.Minute).UTC().Format(time.RFC3339)
+	to := backfilled.EventTime.Add(time.Minute).UTC().Format(time.RFC3339)
+	list := func(timeField string) []time.Time {
+		url := fmt.Sprintf("http://localhost:%d/v1/machines/%d/records?from=%s&to=%s&time_field=%s",
+			testCfg.ApiServer.Port, machineID, from, to, timeField)
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer closeOrLog(resp.Body)
+		checkStatus(t, resp.StatusCode, http.StatusOK)
+
+		var body struct {
+			Records []struct {
+				EventTime time.Time `json:"event_time"`
+			} `json:"records"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		eventTimes := make([]time.Time, len(body.Records))
+		for i, record := range body.Records {
+			eventTimes[i] = record.EventTime
+		}
+		return eventTimes
+	}
+
+	// Act
+	byEventTime := list("event_time")
+	byCreatedAt := list("created_at")
+
+	// Assert
+	if len(byEventTime) != 1 || !byEventTime[0].Equal(backfilled.EventTime) {
+		t.Errorf("expected the backfilled reading, got %v", byEventTime)
+	}
+	if len(byCreatedAt) != 0 {
+		t.Errorf("expected no reading stored 3 hours ago, got %v", byCreatedAt)
+	}
+}
+
+func TestListRecordsRouteInvalidFilters(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/v1/machines/%d/records?from=yesterday&limit=0", testCfg.ApiServer.Port, machineID)
+
+	// Act
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeOrLog(resp.Body)
+
+	// Assert
+	checkStatus(t, resp.StatusCode, http.StatusUnprocessableEntity)
+}
+
+func TestStreamRoute(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/v1/machines/%d/stream", testCfg.ApiServer.Port, machineID)
+	predictURL := fmt.Sprintf("http://localhost:%d/v1/predict", testCfg.ApiServer.Port)
+	jsonData, err := json.Marshal([]postgres_models.Sensor{testSensor()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeOrLog(resp.Body)
+
+	predictResp, err := http.Post(predictURL, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	closeOrLog(predictResp.Body)
+
+	var received bool
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if scanner.Text() == "event: prediction" {
+			received = true
+			break
+		}
+	}
+
+	// Assert
+	checkStatus(t, resp.StatusCode, http.StatusOK)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected text/event-stream content type, got %s", ct)
+	}
+	if !received {
+		t.Error("expected a prediction event")
+	}
+}
+
+func TestShowSchemaRoute(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/v1/schema", testCfg.ApiServer.Port)
+
+	// Act
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeOrLog(resp.Body)
+
+	var body struct {
+		Schema schema.Schema `json:"schema"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	checkStatus(t, resp.StatusCode, http.StatusOK)
+	if len(body.Schema.Sensors) != schema.Default().Len() {
+		t.Errorf("expected %d sensors, got %d", schema.Default().Len(), len(body.Schema.Sensors))
+	}
+}
+
+// testSensor returns a reading of the test machine, with a value for every sensor of the default schema
+func testSensor() postgres_models.Sensor {
+	values := make(map[string]float64)
+	for i, name := range schema.Default().Names() {
+		values[name] = float64(i+1) / 10
+	}
+	return postgres_models.Sensor{MachineID: machineID, Values: values}
+}
+
+// Check if the metrics route exposes the pipeline metrics
+func TestMetricsRoute(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/metrics", testCfg.ApiServer.Port)
+
+	// Act
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeOrLog(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	checkStatus(t, resp.StatusCode, http.StatusOK)
+	if !strings.Contains(string(body), "ml_facade_http_requests_total") {
+		t.Error("expected the http requests metric to be exposed")
+	}
+	if !strings.Contains(string(body), fmt.Sprintf(`ml_facade_machine_reconstruction_error_count{machine_id="%d"}`, machineID)) {
+		t.Error("expected the reconstruction errors of the machine to be exposed")
+	}
+}
+
+// Check if the readiness route reports every dependency
+func TestReadinessRoute(t *testing.T) {
+	// Arrange
+	url := fmt.Sprintf("http://localhost:%d/ready", testCfg.ApiServer.Port)
+
+	// Act
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeOrLog(resp.Body)
+
+	var body struct {
+		Status       string                    `json:"status"`
+		Dependencies map[string]map[string]any `json:"dependencies"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	// RabbitMQ is not started by the tests, but it is not a critical dependency
+	checkStatus(t, resp.StatusCode, http.StatusOK)
+	if body.Status != "degraded" {
+		t.Errorf("expected status 'degraded', got '%s'", body.Status)
+	}
+	for _, name := range []string{"postgres", "redis", "ml_service", "rabbitmq"} {
+		if _, ok := body.Dependencies[name]; !ok {
+			t.Errorf("expected dependency '%s' to be reported", name)
+		}
+	}
 }
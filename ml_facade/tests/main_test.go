@@ -93,7 +93,17 @@ func TestMain(m *testing.M) {
 	if err != nil {
 		fmt.Println(err)
 	}
+	testCfg.GrpcServer.Port, err = findAvailablePort()
+	if err != nil {
+		fmt.Println(err)
+	}
+	testCfg.GrpcServer.StreamBatchSize = 2
+	testCfg.GrpcServer.StreamBatchWait = 100 * time.Millisecond
 	testCfg.Env = "test"
+	testCfg.ApiServer.StreamHeartbeat = time.Second
+	testCfg.ApiServer.IdempotencyWindow = time.Hour
+	testCfg.ApiServer.NDJSONBatchSize = 1
+	testCfg.ApiServer.NDJSONBatchWait = 100 * time.Millisecond
 	testCfg.PostgresDB.Host = postgresHost
 	testCfg.PostgresDB.Port = postgresPort.Port()
 	testCfg.PostgresDB.Username = postgresUsername
@@ -103,6 +113,25 @@ func TestMain(m *testing.M) {
 	testCfg.RedisDB.Port = redisPort.Port()
 	testCfg.MlService.Host = mlServiceHost
 	testCfg.MlService.Port = mlServicePort
+	testCfg.MlService.HealthInterval = time.Second
+	testCfg.MlService.Timeout = 2 * time.Second
+	testCfg.MlService.Breaker.FailureThreshold = 5
+	testCfg.MlService.Breaker.OpenTimeout = 10 * time.Second
+	testCfg.MlService.Breaker.HalfOpenRequests = 1
+	testCfg.MlService.LatenessTolerance = 5 * time.Minute
+	testCfg.MlService.DedupWindow = time.Hour
+	testCfg.Validation.MaxBodyBytes = 1 << 20
+	testCfg.Validation.MinBatchSize = 1
+	testCfg.Validation.MaxBatchSize = 100
+	testCfg.Jobs.Workers = 1
+	testCfg.Jobs.ChunkSize = 1
+	testCfg.Jobs.MaxReadings = 1000
+	testCfg.Jobs.MaxBodyBytes = 1 << 20
+	testCfg.Jobs.PollInterval = 100 * time.Millisecond
+	testCfg.Jobs.Lease = time.Minute
+	testCfg.Import.ChunkSize = 1
+	testCfg.Import.MaxBodyBytes = 1 << 20
+	testCfg.Import.Timeout = time.Minute
 	testCfg.PostgresDB.MaxOpenConns = 25
 	testCfg.PostgresDB.MaxIdleConns = 25
 	testCfg.PostgresDB.MaxIdleTime = 5 * time.Minute
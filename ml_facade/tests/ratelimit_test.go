@@ -0,0 +1,53 @@
+package test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"ml_facade/config"
+	"ml_facade/internal/models/redis_models"
+	"ml_facade/internal/ratelimit"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Check if the limiters of two replicas share the buckets stored in Redis
+func TestSharedRateLimit(t *testing.T) {
+	// Arrange
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", testCfg.RedisDB.RedisDBDsn())
+		},
+	}
+	t.Cleanup(func() { closeOrLog(pool) })
+	store := &redis_models.RateLimitModel{RedisDB: pool}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	cfg := config.CfgLimiter{Rps: 1, Burst: 3, Enabled: true, Redis: true}
+	first := ratelimit.New(cfg, "shared-test", store, logger)
+	second := ratelimit.New(cfg, "shared-test", store, logger)
+	ctx := context.Background()
+
+	// Act
+	var allowed int
+	for i := 0; i < 3; i++ {
+		for _, limiter := range []*ratelimit.Limiter{first, second} {
+			if limiter.Allow(ctx, "client").Allowed {
+				allowed++
+			}
+		}
+	}
+	decision := first.Allow(ctx, "client")
+
+	// Assert
+	if allowed != 3 {
+		t.Errorf("expected the replicas to be allowed 3 requests together, got %d", allowed)
+	}
+	if decision.Allowed || decision.Remaining != 0 || decision.RetryAfter <= 0 {
+		t.Errorf("expected the shared bucket to be empty, got %+v", decision)
+	}
+	if first.Len() != 0 || second.Len() != 0 {
+		t.Errorf("expected no local bucket, got %d and %d", first.Len(), second.Len())
+	}
+}
@@ -0,0 +1,57 @@
+package test
+
+import (
+	"context"
+	"ml_facade/internal/models/redis_models"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Check if setting and deleting a threshold tells the subscribed replicas
+func TestThresholdInvalidations(t *testing.T) {
+	// Arrange
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", testCfg.RedisDB.RedisDBDsn())
+		},
+	}
+	t.Cleanup(func() { closeOrLog(pool) })
+	model := &redis_models.ThresholdModel{RedisDB: pool}
+	invalidatedMachineID := machineID + 2
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	machineIDs, err := model.SubscribeInvalidations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receive := func() int {
+		select {
+		case id := <-machineIDs:
+			return id
+		case <-ctx.Done():
+			t.Fatal("expected an invalidation")
+			return 0
+		}
+	}
+
+	// Act
+	err = model.Insert(ctx, redis_models.Threshold{MachineID: invalidatedMachineID, Threshold: 0.5}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inserted := receive()
+
+	err = model.Delete(ctx, invalidatedMachineID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted := receive()
+
+	// Assert
+	if inserted != invalidatedMachineID || deleted != invalidatedMachineID {
+		t.Errorf("expected machine %d to be invalidated twice, got %d and %d", invalidatedMachineID, inserted, deleted)
+	}
+}
@@ -1 +1 @@
-curl -i -X POST http://localhost:4000/v1/threshold -d '{"machine_id": 7, "threshold": 0.02}'
+curl -i -X POST http://localhost:4000/v1/threshold -H "Authorization: Bearer ${ADMIN_API_KEY}" -d '{"machine_id": 7, "threshold": 0.02}'
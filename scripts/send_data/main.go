@@ -40,7 +40,7 @@ func toFloat(value string) float64 {
 func sendPredictionAPI(client *http.Client, data []SensorData, counter *uint64) {
 	var listData []SensorDataPayload
 	for _, sensorData := range data {
-		listData = append(listData, sensorData.SensorDataPayload)
+		listData = append(listData, sensorData.Payload)
 	}
 	var machineStatuses []string
 	for _, status := range data {
@@ -81,8 +81,8 @@ func sendPredictionAPI(client *http.Client, data []SensorData, counter *uint64)
 func sendPredictionRabbit(ch *amqp.Channel, data SensorData, counter *uint64) {
 	rabbitmqQueue := "test"
 
-	// Convert data to JSON
-	message, err := json.Marshal(data)
+	// Convert data to JSON, the machine status is not part of the sensor schema
+	message, err := json.Marshal(data.Payload)
 	if err != nil {
 		log.Printf("Failed to marshal message: %v", err)
 		return
@@ -194,8 +194,32 @@ func sendBatch(
 	}
 }
 
+// sensorColumns returns the index of the sensor columns of the CSV header, every
+// column being a sensor except the unnamed index, the timestamp and the machine status
+func sensorColumns(header []string) map[int]string {
+	columns := make(map[int]string)
+	for i, name := range header {
+		if name == "" || name == timestampColumn || name == machineStatusColumn {
+			continue
+		}
+		columns[i] = name
+	}
+	return columns
+}
+
+// columnIndex returns the index of the column in the CSV header, or -1 if it is missing
+func columnIndex(header []string, name string) int {
+	for i, column := range header {
+		if column == name {
+			return i
+		}
+	}
+	return -1
+}
+
 func main() {
 	var rps = RequestPerSecond{}
+	var machineID int
 
 	transport := &http.Transport{
 		MaxIdleConns:      100,
@@ -208,8 +232,14 @@ func main() {
 	flag.BoolVar(&useRabbitmq, "rabbitmq", false, "Use RabbitMQ for sending data")
 	flag.IntVar(&rps.rate, "requests", 10000, "Requests per second")
 	flag.IntVar(&rps.rateBurst, "requests-burst", 50, "Requests per second burst")
+	flag.IntVar(&machineID, "machine-id", 7, "Machine ID the readings are sent for")
+	apiKey := flag.String("api-key", os.Getenv("ADMIN_API_KEY"), "API key with the predict:write scope")
 	flag.Parse()
 
+	if *apiKey != "" {
+		apiHeaders["Authorization"] = "Bearer " + *apiKey
+	}
+
 	// Get the current working directory
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -227,10 +257,12 @@ func main() {
 	defer closeOrLog(csvFile)
 
 	reader := csv.NewReader(csvFile)
-	_, err = reader.Read()
+	header, err := reader.Read()
 	if err != nil {
 		log.Fatalf("Error reading CSV headers: %v", err)
 	}
+	columns := sensorColumns(header)
+	statusIndex := columnIndex(header, machineStatusColumn)
 
 	limiter := rate.NewLimiter(rate.Every(time.Second/time.Duration(rps.rate)), rps.rateBurst)
 
@@ -295,63 +327,14 @@ func main() {
 			log.Fatalf("Error reading CSV row: %v", err)
 		}
 
-		data := SensorData{
-			SensorDataPayload: SensorDataPayload{
-				MachineID: 7,
-				Sensor00:  toFloat(row[2]),
-				Sensor01:  toFloat(row[3]),
-				Sensor02:  toFloat(row[4]),
-				Sensor03:  toFloat(row[5]),
-				Sensor04:  toFloat(row[6]),
-				Sensor05:  toFloat(row[7]),
-				Sensor06:  toFloat(row[8]),
-				Sensor07:  toFloat(row[9]),
-				Sensor08:  toFloat(row[10]),
-				Sensor09:  toFloat(row[11]),
-				Sensor10:  toFloat(row[12]),
-				Sensor11:  toFloat(row[13]),
-				Sensor12:  toFloat(row[14]),
-				Sensor13:  toFloat(row[15]),
-				Sensor14:  toFloat(row[16]),
-				Sensor15:  toFloat(row[17]),
-				Sensor16:  toFloat(row[18]),
-				Sensor17:  toFloat(row[19]),
-				Sensor18:  toFloat(row[20]),
-				Sensor19:  toFloat(row[21]),
-				Sensor20:  toFloat(row[22]),
-				Sensor21:  toFloat(row[23]),
-				Sensor22:  toFloat(row[24]),
-				Sensor23:  toFloat(row[25]),
-				Sensor24:  toFloat(row[26]),
-				Sensor25:  toFloat(row[27]),
-				Sensor26:  toFloat(row[28]),
-				Sensor27:  toFloat(row[29]),
-				Sensor28:  toFloat(row[30]),
-				Sensor29:  toFloat(row[31]),
-				Sensor30:  toFloat(row[32]),
-				Sensor31:  toFloat(row[33]),
-				Sensor32:  toFloat(row[34]),
-				Sensor33:  toFloat(row[35]),
-				Sensor34:  toFloat(row[36]),
-				Sensor35:  toFloat(row[37]),
-				Sensor36:  toFloat(row[38]),
-				Sensor37:  toFloat(row[39]),
-				Sensor38:  toFloat(row[40]),
-				Sensor39:  toFloat(row[41]),
-				Sensor40:  toFloat(row[42]),
-				Sensor41:  toFloat(row[43]),
-				Sensor42:  toFloat(row[44]),
-				Sensor43:  toFloat(row[45]),
-				Sensor44:  toFloat(row[46]),
-				Sensor45:  toFloat(row[47]),
-				Sensor46:  toFloat(row[48]),
-				Sensor47:  toFloat(row[49]),
-				Sensor48:  toFloat(row[50]),
-				Sensor49:  toFloat(row[51]),
-				Sensor50:  toFloat(row[52]),
-				Sensor51:  toFloat(row[53]),
-			},
-			MachineStatus: row[54],
+		payload := SensorDataPayload{"machine_id": machineID}
+		for i, name := range columns {
+			payload[name] = toFloat(row[i])
+		}
+
+		data := SensorData{Payload: payload}
+		if statusIndex >= 0 {
+			data.MachineStatus = row[statusIndex]
 		}
 
 		dataCh <- data